@@ -0,0 +1,460 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type AccessGroup struct {
+	Name  string   `yaml:"name"`
+	Users []string `yaml:"users"`
+}
+
+// AccessPolicyConfig describes a policy either by its resource or by the
+// path of a process group and an optional resource kind like data,
+// provenance-data, operation or policies.
+type AccessPolicyConfig struct {
+	Resource string   `yaml:"resource,omitempty"`
+	Path     string   `yaml:"path,omitempty"`
+	Kind     string   `yaml:"kind,omitempty"`
+	Action   string   `yaml:"action"`
+	Users    []string `yaml:"users,omitempty"`
+	Groups   []string `yaml:"groups,omitempty"`
+}
+
+type AccessConfig struct {
+	Users    []string             `yaml:"users"`
+	Groups   []AccessGroup        `yaml:"groups"`
+	Policies []AccessPolicyConfig `yaml:"policies"`
+}
+
+func LoadAccessConfig(file string) (*AccessConfig, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &AccessConfig{}
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+type AccessChange struct {
+	Operation string `json:"operation"`
+	Target    string `json:"target"`
+	Detail    string `json:"detail"`
+
+	state *accessState
+	apply func(*accessState) error
+}
+
+type AccessPlan []*AccessChange
+
+func (p AccessPlan) Fprint(w io.Writer) {
+	if len(p) == 0 {
+		fmt.Fprintln(w, "no changes")
+		return
+	}
+
+	for _, c := range p {
+		fmt.Fprintf(w, "%-14s %s", c.Operation, c.Target)
+		if len(c.Detail) > 0 {
+			fmt.Fprintf(w, " (%s)", c.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+type accessState struct {
+	client *Client
+	users  map[string]*User
+	groups map[string]*UserGroup
+}
+
+func (s *accessState) userIds(identities []string) ([]string, error) {
+	result := []string{}
+	for _, identity := range identities {
+		user, ok := s.users[identity]
+		if !ok {
+			return nil, fmt.Errorf("user %v: %w", identity, ErrNotFound)
+		}
+
+		result = append(result, user.ID)
+	}
+
+	return result, nil
+}
+
+func (s *accessState) groupIds(identities []string) ([]string, error) {
+	result := []string{}
+	for _, identity := range identities {
+		group, ok := s.groups[identity]
+		if !ok {
+			return nil, fmt.Errorf("user group %v: %w", identity, ErrNotFound)
+		}
+
+		result = append(result, group.ID)
+	}
+
+	return result, nil
+}
+
+func (s *accessState) identities(ids []string, groups bool) []string {
+	result := []string{}
+	for _, id := range ids {
+		name := id
+		if groups {
+			for k, g := range s.groups {
+				if g.ID == id {
+					name = k
+				}
+			}
+		} else {
+			for k, u := range s.users {
+				if u.ID == id {
+					name = k
+				}
+			}
+		}
+
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// PlanAccess compares the configuration with the users, groups and policies
+// of NiFi. Users and groups not in the configuration are kept, the members of
+// configured groups and policies are replaced. Policies may only reference
+// users and groups, which are configured or exist in NiFi, and entries for
+// the same action and resource are merged.
+func (c *Client) PlanAccess(config *AccessConfig) (AccessPlan, error) {
+	users, err := c.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := c.UserGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &accessState{
+		client: c,
+		users:  map[string]*User{},
+		groups: map[string]*UserGroup{},
+	}
+
+	for _, u := range users {
+		state.users[u.Identity] = u
+	}
+
+	for _, g := range groups {
+		state.groups[g.Identity] = g
+	}
+
+	plan := AccessPlan{}
+
+	declared := append([]string{}, config.Users...)
+	for _, g := range config.Groups {
+		declared = append(declared, g.Users...)
+	}
+
+	for _, identity := range union(nil, declared) {
+		if _, ok := state.users[identity]; ok {
+			continue
+		}
+
+		identity := identity
+		plan = append(plan, &AccessChange{
+			Operation: "create user",
+			Target:    identity,
+			apply: func(s *accessState) error {
+				user, err := s.client.CreateUser(identity)
+				if err != nil {
+					return err
+				}
+
+				s.users[identity] = user
+				return nil
+			},
+		})
+	}
+
+	for _, g := range config.Groups {
+		g := g
+		live, ok := state.groups[g.Name]
+
+		if !ok {
+			plan = append(plan, &AccessChange{
+				Operation: "create group",
+				Target:    g.Name,
+				Detail:    strings.Join(g.Users, ", "),
+				apply: func(s *accessState) error {
+					ids, err := s.userIds(g.Users)
+					if err != nil {
+						return err
+					}
+
+					group, err := s.client.CreateUserGroup(g.Name, ids)
+					if err != nil {
+						return err
+					}
+
+					s.groups[g.Name] = group
+					return nil
+				},
+			})
+
+			continue
+		}
+
+		current := state.identities(live.Users, false)
+		if !sameSet(current, g.Users) {
+			plan = append(plan, &AccessChange{
+				Operation: "update group",
+				Target:    g.Name,
+				Detail:    setDiff(current, g.Users),
+				apply: func(s *accessState) error {
+					ids, err := s.userIds(g.Users)
+					if err != nil {
+						return err
+					}
+
+					group := *s.groups[g.Name]
+					group.Users = ids
+
+					updated, err := s.client.UpdateUserGroup(&group)
+					if err != nil {
+						return err
+					}
+
+					s.groups[g.Name] = updated
+					return nil
+				},
+			})
+		}
+	}
+
+	var paths *pathIndex
+
+	for _, p := range config.Policies {
+		if len(p.Resource) == 0 && len(p.Path) > 0 {
+			paths, err = c.pathIndex(ProcessGroup)
+			if err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	declaredGroups := []string{}
+	for _, g := range config.Groups {
+		declaredGroups = append(declaredGroups, g.Name)
+	}
+
+	policies := []*plannedPolicy{}
+	byResource := map[string]*plannedPolicy{}
+
+	for _, p := range config.Policies {
+		resource, err := policyResource(&p, paths)
+		if err != nil {
+			return nil, err
+		}
+
+		target := p.Action + " " + resource
+		if len(p.Path) > 0 {
+			target += " [" + p.Path + "]"
+		}
+
+		for _, identity := range p.Users {
+			if _, ok := state.users[identity]; !ok && !contains(declared, identity) {
+				return nil, fmt.Errorf("policy %v: user %v: %w", target, identity, ErrNotFound)
+			}
+		}
+
+		for _, identity := range p.Groups {
+			if _, ok := state.groups[identity]; !ok && !contains(declaredGroups, identity) {
+				return nil, fmt.Errorf("policy %v: user group %v: %w", target, identity, ErrNotFound)
+			}
+		}
+
+		key := p.Action + " " + resource
+		if planned, ok := byResource[key]; ok {
+			planned.config.Users = union(planned.config.Users, p.Users)
+			planned.config.Groups = union(planned.config.Groups, p.Groups)
+			continue
+		}
+
+		planned := &plannedPolicy{
+			config:   p,
+			resource: resource,
+			target:   target,
+		}
+
+		planned.config.Users = union(nil, p.Users)
+		planned.config.Groups = union(nil, p.Groups)
+
+		byResource[key] = planned
+		policies = append(policies, planned)
+	}
+
+	for _, planned := range policies {
+		p, resource, target := planned.config, planned.resource, planned.target
+
+		live, err := c.GetPolicy(p.Action, resource)
+		if errors.Is(err, ErrNotFound) {
+			plan = append(plan, &AccessChange{
+				Operation: "create policy",
+				Target:    target,
+				Detail:    strings.Join(append(append([]string{}, p.Users...), p.Groups...), ", "),
+				apply: func(s *accessState) error {
+					users, groups, err := s.tenantIds(&p)
+					if err != nil {
+						return err
+					}
+
+					_, err = s.client.CreatePolicy(p.Action, resource, users, groups)
+					return err
+				},
+			})
+
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		currentUsers := state.identities(live.Users, false)
+		currentGroups := state.identities(live.UserGroups, true)
+
+		if !sameSet(currentUsers, p.Users) || !sameSet(currentGroups, p.Groups) {
+			plan = append(plan, &AccessChange{
+				Operation: "update policy",
+				Target:    target,
+				Detail:    setDiff(append(currentUsers, currentGroups...), append(append([]string{}, p.Users...), p.Groups...)),
+				apply: func(s *accessState) error {
+					users, groups, err := s.tenantIds(&p)
+					if err != nil {
+						return err
+					}
+
+					policy := *live
+					policy.Users = users
+					policy.UserGroups = groups
+
+					_, err = s.client.UpdatePolicy(&policy)
+					return err
+				},
+			})
+		}
+	}
+
+	for _, change := range plan {
+		change.state = state
+	}
+
+	return plan, nil
+}
+
+type plannedPolicy struct {
+	config   AccessPolicyConfig
+	resource string
+	target   string
+}
+
+// Apply applies the changes in order and stops at the first error.
+func (p AccessPlan) Apply() error {
+	for _, change := range p {
+		err := change.apply(change.state)
+		if err != nil {
+			return fmt.Errorf("%v %v: %w", change.Operation, change.Target, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *accessState) tenantIds(p *AccessPolicyConfig) ([]string, []string, error) {
+	users, err := s.userIds(p.Users)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups, err := s.groupIds(p.Groups)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return users, groups, nil
+}
+
+// policyResource returns the resource of the policy. Paths are resolved with
+// the process groups, which are indexed once for all policies.
+func policyResource(p *AccessPolicyConfig, paths *pathIndex) (string, error) {
+	if len(p.Resource) > 0 {
+		return p.Resource, nil
+	}
+
+	if len(p.Path) == 0 {
+		return "", fmt.Errorf("policy %v: resource or path is required", p.Action)
+	}
+
+	group, err := paths.find(p.Path)
+	if err != nil {
+		return "", err
+	}
+
+	resource := "/process-groups/" + group.ID
+	if len(p.Kind) > 0 {
+		resource = "/" + strings.Trim(p.Kind, "/") + resource
+	}
+
+	return resource, nil
+}
+
+func sameSet(a []string, b []string) bool {
+	return len(union(nil, a)) == len(union(nil, b)) && len(without(union(nil, a), b)) == 0
+}
+
+func setDiff(current []string, expected []string) string {
+	changes := []string{}
+
+	for _, v := range without(expected, current) {
+		changes = append(changes, "+"+v)
+	}
+
+	for _, v := range without(current, expected) {
+		changes = append(changes, "-"+v)
+	}
+
+	sort.Strings(changes)
+
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newAccessTestClient serves the user admin, the group ops and no policies.
+func newAccessTestClient(t *testing.T) *Client {
+	mux := http.NewServeMux()
+
+	json := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+
+	mux.HandleFunc("/nifi-api/tenants/users", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"users":[{"id":"u1","revision":{"version":1},"component":{"id":"u1","identity":"admin"}}]}`)
+	})
+
+	mux.HandleFunc("/nifi-api/tenants/user-groups", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"userGroups":[{"id":"g1","revision":{"version":1},"component":{"id":"g1","identity":"ops"}}]}`)
+	})
+
+	mux.HandleFunc("/nifi-api/policies/", http.NotFound)
+
+	return newTestClient(t, mux)
+}
+
+func TestPlanAccessUnknownTenants(t *testing.T) {
+	client := newAccessTestClient(t)
+
+	tests := []AccessPolicyConfig{
+		{Resource: "/flow", Action: ReadAction, Users: []string{"admin", "amdin"}},
+		{Resource: "/flow", Action: ReadAction, Groups: []string{"ops", "dev"}},
+	}
+
+	for _, p := range tests {
+		_, err := client.PlanAccess(&AccessConfig{
+			Users:    []string{"alice"},
+			Groups:   []AccessGroup{{Name: "team", Users: []string{"alice"}}},
+			Policies: []AccessPolicyConfig{p},
+		})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%v %v: error = %v, want %v", p.Users, p.Groups, err, ErrNotFound)
+		}
+	}
+}
+
+func TestPlanAccessMergesPolicies(t *testing.T) {
+	client := newAccessTestClient(t)
+
+	plan, err := client.PlanAccess(&AccessConfig{
+		Users:  []string{"alice"},
+		Groups: []AccessGroup{{Name: "team", Users: []string{"alice"}}},
+		Policies: []AccessPolicyConfig{
+			{Resource: "/flow", Action: ReadAction, Users: []string{"alice", "admin"}},
+			{Resource: "/counters", Action: ReadAction, Groups: []string{"team"}},
+			{Resource: "/flow", Action: ReadAction, Users: []string{"admin"}, Groups: []string{"ops", "team"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+	plan.Fprint(&output)
+
+	expected := `create user    alice
+create group   team (alice)
+create policy  read /flow (alice, admin, ops, team)
+create policy  read /counters (team)
+`
+
+	if output.String() != expected {
+		t.Errorf("plan:\n%v\nwant:\n%v", output.String(), expected)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrUnsupportedLogin = fmt.Errorf("unsupported login method")
+)
+
+type LoginMethod string
+
+const (
+	// AnonymousLogin is used by unsecured servers.
+	AnonymousLogin   LoginMethod = "anonymous"
+	CertificateLogin LoginMethod = "certificate"
+	PasswordLogin    LoginMethod = "password"
+	// TokenLogin needs a bearer token of an external provider like OIDC.
+	TokenLogin LoginMethod = "token"
+	// KerberosLogin gets a token from /access/kerberos with SPNEGO.
+	KerberosLogin LoginMethod = "kerberos"
+)
+
+// AccessInfo is the access configuration of the server and the access status
+// of the client options used to detect it.
+type AccessInfo struct {
+	SupportsLogin bool   `json:"supportsLogin"`
+	Kerberos      bool   `json:"kerberos"`
+	Identity      string `json:"identity"`
+	Status        string `json:"status"`
+	Message       string `json:"message"`
+}
+
+func (a *AccessInfo) Method() LoginMethod {
+	if a.Status == "ACTIVE" {
+		if len(a.Identity) == 0 || a.Identity == "anonymous" {
+			return AnonymousLogin
+		}
+
+		return CertificateLogin
+	}
+
+	if a.SupportsLogin {
+		return PasswordLogin
+	}
+
+	if a.Kerberos {
+		return KerberosLogin
+	}
+
+	return TokenLogin
+}
+
+// DetectAccess reads /access/config and /access without a token and asks
+// /access/kerberos for a SPNEGO challenge. A client certificate in the options
+// is used and results in CertificateLogin.
+func DetectAccess(server *url.URL, options ...Option) (*AccessInfo, error) {
+	o := NewOptions(options...)
+	o.TokenSource = nil
+
+	client, err := NewHttpClientWithOptions(o)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AccessInfo{}
+
+	var config struct {
+		Config struct {
+			SupportsLogin bool `json:"supportsLogin"`
+		} `json:"config"`
+	}
+
+	err = getAccessJSON(client, server, "/access/config", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	info.SupportsLogin = config.Config.SupportsLogin
+
+	info.Kerberos, err = detectKerberos(client, server)
+	if err != nil {
+		return nil, err
+	}
+
+	var status struct {
+		AccessStatus struct {
+			Identity string `json:"identity"`
+			Status   string `json:"status"`
+			Message  string `json:"message"`
+		} `json:"accessStatus"`
+	}
+
+	err = getAccessJSON(client, server, "/access", &status)
+	if IsStatus(err, http.StatusUnauthorized) || IsStatus(err, http.StatusForbidden) {
+		info.Status = "UNKNOWN"
+		return info, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	info.Identity = status.AccessStatus.Identity
+	info.Status = status.AccessStatus.Status
+	info.Message = status.AccessStatus.Message
+
+	return info, nil
+}
+
+// detectKerberos checks, if /access/kerberos answers a request without ticket
+// with a Negotiate challenge. Servers without SPNEGO answer with 409.
+func detectKerberos(client *HttpClient, server *url.URL) (bool, error) {
+	u := *server
+	u.Path = "/nifi-api/access/kerberos"
+
+	request, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return false, err
+	}
+
+	defer response.Body.Close()
+	ioutil.ReadAll(response.Body)
+
+	return response.StatusCode == http.StatusUnauthorized && strings.HasPrefix(response.Header.Get("WWW-Authenticate"), "Negotiate"), nil
+}
+
+func getAccessJSON(client *HttpClient, server *url.URL, path string, v interface{}) error {
+	u := *server
+	u.Path = "/nifi-api" + path
+
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode > 299 {
+		return &HTTPError{
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       string(body),
+		}
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// LoginAuto detects the access configuration and logs in with the password,
+// the client certificate, the token source or the Kerberos config of the
+// options. Kerberos is also used without username, if the server supports
+// both.
+func LoginAuto(server *url.URL, username string, password string, options ...Option) (*Client, error) {
+	info, err := DetectAccess(server, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	o := NewOptions(options...)
+
+	switch info.Method() {
+	case AnonymousLogin, CertificateLogin:
+		return ConnectWithOptions(server, options...)
+	case PasswordLogin:
+		if len(username) == 0 && info.Kerberos {
+			return LoginKerberos(server, o.kerberos(), options...)
+		}
+
+		return LoginWithOptions(server, username, password, options...)
+	case KerberosLogin:
+		if o.TokenSource != nil {
+			return ConnectWithOptions(server, options...)
+		}
+
+		return LoginKerberos(server, o.kerberos(), options...)
+	default:
+		if o.TokenSource == nil {
+			return nil, fmt.Errorf("%v: %w", info.Method(), ErrUnsupportedLogin)
+		}
+
+		return ConnectWithOptions(server, options...)
+	}
+}
+
+// Logout ends the session on the server and removes the token from the
+// client and the token store. Servers without /access/logout are ignored.
+func (c *Client) Logout() error {
+	var err error
+
+	if c.status.hasToken() {
+		var response *http.Response
+		response, err = c.Do(Delete, c.URL("/access/logout"), nil, "")
+		if err == nil {
+			response.Body.Close()
+		} else if IsStatus(err, http.StatusNotFound) || IsStatus(err, http.StatusMethodNotAllowed) || IsStatus(err, http.StatusUnauthorized) {
+			err = nil
+		}
+	}
+
+	if c.options != nil && c.options.TokenStore != nil && len(c.status.loginName()) > 0 {
+		serr := c.options.TokenStore.Delete(c.status.Server, c.status.loginName())
+		if err == nil {
+			err = serr
+		}
+	}
+
+	c.status.clear()
+	c.root.clear()
+
+	return err
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestLogoutWithNodeCopies(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nifi-api/access/logout", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/nifi-api/flow/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+
+	client := newTestClient(t, mux)
+	client.status.Token = "token"
+	client.status.Cookies = map[string]string{"session": "1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func(node *Client) {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				node.Get("/flow/about")
+			}
+		}(client.Node("node"))
+	}
+
+	err := client.Logout()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.status.hasToken() || len(client.status.GetCookies()) > 0 {
+		t.Error("logout didn't clear the status")
+	}
+}
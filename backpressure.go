@@ -0,0 +1,167 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type QueueHealth struct {
+	Component       *Component `json:"component"`
+	Source          string     `json:"source"`
+	Destination     string     `json:"destination"`
+	FlowFilesQueued int64      `json:"flowFilesQueued"`
+	BytesQueued     int64      `json:"bytesQueued"`
+	PercentUseCount float64    `json:"percentUseCount"`
+	PercentUseBytes float64    `json:"percentUseBytes"`
+	Growth          int64      `json:"growth"`
+	Growing         bool       `json:"growing"`
+}
+
+func NewQueueHealth(c *Component) *QueueHealth {
+	q := &QueueHealth{
+		Component: c,
+	}
+
+	q.Source, _ = c.Attributes["sourceName"].(string)
+	q.Destination, _ = c.Attributes["destinationName"].(string)
+	q.PercentUseCount, _ = c.Attributes["percentUseCount"].(float64)
+	q.PercentUseBytes, _ = c.Attributes["percentUseBytes"].(float64)
+
+	if val, ok := c.Attributes["flowFilesQueued"].(float64); ok {
+		q.FlowFilesQueued = int64(val)
+	}
+
+	if val, ok := c.Attributes["bytesQueued"].(float64); ok {
+		q.BytesQueued = int64(val)
+	}
+
+	return q
+}
+
+// Severity is the higher usage of the object and the size threshold in
+// percent.
+func (q *QueueHealth) Severity() float64 {
+	if q.PercentUseBytes > q.PercentUseCount {
+		return q.PercentUseBytes
+	}
+
+	return q.PercentUseCount
+}
+
+type QueueReport []*QueueHealth
+
+func (r QueueReport) Len() int      { return len(r) }
+func (r QueueReport) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r QueueReport) Less(i, j int) bool {
+	if r[i].Severity() == r[j].Severity() {
+		return r[i].Growth > r[j].Growth
+	}
+
+	return r[i].Severity() > r[j].Severity()
+}
+
+func (r QueueReport) Fprint(w io.Writer) {
+	for _, q := range r {
+		fmt.Fprintf(w, "%5.1f%% %s: %s -> %s (%v flowfiles, %v bytes)",
+			q.Severity(), q.Component.Path, q.Source, q.Destination, q.FlowFilesQueued, q.BytesQueued)
+
+		if q.Growing {
+			fmt.Fprintf(w, " growing %+d", q.Growth)
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+// Backpressure reports all connections below ids with a queue above threshold
+// percent of the object or size backpressure threshold, sorted by severity.
+func (c *Client) Backpressure(ids []string, threshold float64) (QueueReport, error) {
+	connections, err := c.All(ids, Connection, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := QueueReport{}
+	for _, connection := range connections {
+		q := NewQueueHealth(connection)
+		if q.Severity() >= threshold {
+			report = append(report, q)
+		}
+	}
+
+	sort.Sort(report)
+
+	return report, nil
+}
+
+// BackpressureTrend samples the connections and additionally reports the
+// connections, which queue grew between every sample.
+func (c *Client) BackpressureTrend(ids []string, threshold float64, samples int, interval time.Duration) (QueueReport, error) {
+	if samples < 2 {
+		return nil, fmt.Errorf("backpressure trend needs at least 2 samples")
+	}
+
+	first := map[string]*QueueHealth{}
+	last := map[string]*QueueHealth{}
+	growing := map[string]bool{}
+
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		connections, err := c.All(ids, Connection, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, connection := range connections {
+			q := NewQueueHealth(connection)
+
+			if i == 0 {
+				first[connection.ID] = q
+				growing[connection.ID] = true
+			} else if prev, ok := last[connection.ID]; ok {
+				growing[connection.ID] = growing[connection.ID] && q.FlowFilesQueued > prev.FlowFilesQueued
+			} else {
+				growing[connection.ID] = false
+			}
+
+			last[connection.ID] = q
+		}
+	}
+
+	report := QueueReport{}
+	for id, q := range last {
+		if f, ok := first[id]; ok {
+			q.Growth = q.FlowFilesQueued - f.FlowFilesQueued
+			q.Growing = growing[id]
+		}
+
+		if q.Growing || q.Severity() >= threshold {
+			report = append(report, q)
+		}
+	}
+
+	sort.Sort(report)
+
+	return report, nil
+}
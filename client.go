@@ -18,19 +18,37 @@ package nifi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+	"sync"
 )
 
 var (
 	ErrInvalidFormat = fmt.Errorf("invalid response fornat")
 )
 
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+func IsStatus(err error, code int) bool {
+	var e *HTTPError
+	return errors.As(err, &e) && e.StatusCode == code
+}
+
 type Method string
 
 const (
@@ -48,109 +66,170 @@ type RunningStatus struct {
 }
 
 type Client struct {
-	client *HttpClient
-	server *url.URL
-	status *Status
+	client  *HttpClient
+	server  *url.URL
+	status  *Status
+	options *Options
+	node    string
+	group   string
+
+	root *rootCache
+}
+
+// rootCache holds the root group, which is loaded once and shared by the
+// copies of the client returned by Node.
+type rootCache struct {
+	mutex     sync.Mutex
+	component *Component
+}
+
+func (r *rootCache) clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	root *Component
+	r.component = nil
 }
 
 type ComponentFilter func(*Component) bool
 
 func Connect(server *url.URL, certFile string, password string, ca string, insecureSkipVerify bool) (*Client, error) {
-	client, err := NewHttpCertClient(certFile, password, ca, insecureSkipVerify)
+	return ConnectWithOptions(server,
+		WithPKCS12(certFile, password),
+		WithCA(ca),
+		WithInsecureSkipVerify(insecureSkipVerify),
+	)
+}
+
+// ConnectPEM connects with a client certificate and key from PEM files.
+func ConnectPEM(server *url.URL, certFile string, keyFile string, password string, ca string, insecureSkipVerify bool) (*Client, error) {
+	return ConnectWithOptions(server,
+		WithPEMKeyPair(certFile, keyFile, password),
+		WithCA(ca),
+		WithInsecureSkipVerify(insecureSkipVerify),
+	)
+}
+
+// ConnectWithOptions connects with a client certificate, which is set by
+// WithPKCS12, WithPEMKeyPair, WithClientCertificate or WithTLSConfig, or with
+// the bearer tokens of WithTokenSource.
+func ConnectWithOptions(server *url.URL, options ...Option) (*Client, error) {
+	o := NewOptions(options...)
+
+	client, err := NewHttpClientWithOptions(o)
 	if err != nil {
 		return nil, err
 	}
 
 	rc := &Client{
-		client: client,
-		server: server,
+		client:  client,
+		server:  server,
+		options: o,
+		root:    &rootCache{},
 		status: &Status{
 			Server:   server.String(),
-			Insecure: insecureSkipVerify,
+			CA:       o.CA,
+			Insecure: o.InsecureSkipVerify,
+			source:   o.TokenSource,
 		},
 	}
 
-	root, err := rc.Root()
+	_, err = rc.Root()
 	if err != nil {
 		return nil, err
 	}
 
-	rc.root = root
-
 	return rc, nil
 }
 
+// Login authenticates with username and password. The options are, by
+// position, insecureSkipVerify and noCache as bool. New code should use
+// LoginWithOptions.
 func Login(server *url.URL, username string, password string, ca string, options ...interface{}) (*Client, error) {
-	skip := false
+	opts := []Option{WithCA(ca)}
+
 	if len(options) > 0 {
 		val, ok := options[0].(bool)
 		if ok {
-			skip = val
+			opts = append(opts, WithInsecureSkipVerify(val))
 		}
 	}
 
-	noCache := false
 	if len(options) > 1 {
 		val, ok := options[1].(bool)
-		if ok {
-			noCache = val
+		if ok && val {
+			opts = append(opts, WithoutTokenCache())
 		}
 	}
 
-	status := &Status{}
-	err := status.Load("./token.yaml")
-	if !noCache && err == nil && username == status.User &&
-		(server == nil || server.String() == status.Server) &&
-		status.Expire.After(time.Now()) {
+	return LoginWithOptions(server, username, password, opts...)
+}
+
+func LoginWithOptions(server *url.URL, username string, password string, options ...Option) (*Client, error) {
+	return login(server, username, NewOptions(options...), func(u *url.URL) (*http.Request, error) {
+		data := url.Values{}
+		data.Set("username", username)
+		data.Set("password", password)
 
-		client, err := NewHttpClient(status.CA, status.Insecure)
+		u.Path = "/nifi-api/access/token"
+
+		request, err := http.NewRequest("POST", u.String(), strings.NewReader(data.Encode()))
 		if err != nil {
 			return nil, err
 		}
 
-		url, err := url.ParseRequestURI(status.Server)
-		if err == nil {
-			c := &Client{
-				client: client,
-				server: url,
-				status: status,
-			}
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-			root, err := c.Root()
-			if err == nil {
-				c.root = root
-				return c, nil
-			}
-
-		}
-	}
+		return request, nil
+	})
+}
 
-	client, err := NewHttpClient(ca, skip)
+// login reuses a cached token of the user or requests a new one with the
+// request created by newRequest for a copy of the server url.
+func login(server *url.URL, username string, o *Options, newRequest func(u *url.URL) (*http.Request, error)) (*Client, error) {
+	client, err := NewHttpClientWithOptions(o)
 	if err != nil {
 		return nil, err
 	}
 
-	data := url.Values{}
-	data.Set("username", username)
-	data.Set("password", password)
+	key := ""
+	if server != nil {
+		key = server.String()
+	}
+
+	status, err := o.TokenStore.Load(key, username)
+	if err == nil {
+		err = o.validateToken(status.Token)
+		if err == nil {
+			var u *url.URL
+			u, err = url.ParseRequestURI(status.Server)
+			if err == nil {
+				return &Client{
+					client:  client,
+					server:  u,
+					status:  status,
+					options: o,
+					root:    &rootCache{},
+				}, nil
+			}
+		}
+
+		o.logf("cached token for %v on %v is invalid: %v", username, status.Server, err)
+	}
 
 	u := *server
-	u.Path = "/nifi-api/access/token"
 
-	request, err := http.NewRequest("POST", u.String(), strings.NewReader(data.Encode()))
+	request, err := newRequest(&u)
 	if err != nil {
 		return nil, err
 	}
 
-	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-
 	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
 	}
 
+	defer response.Body.Close()
+
 	token, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return nil, err
@@ -160,28 +239,31 @@ func Login(server *url.URL, username string, password string, ca string, options
 		return nil, fmt.Errorf("login: %v (%v)", string(token), response.Status)
 	}
 
-	status, err = NewStatus(server, string(token), response.Cookies(), ca, skip)
+	status, err = NewStatus(server, string(token), response.Cookies(), o.CA, o.InsecureSkipVerify)
 	if err != nil {
 		return nil, err
 	}
 
-	if !noCache {
-		status.Save("./token.yaml")
+	status.Login = username
+
+	err = o.TokenStore.Save(status)
+	if err != nil {
+		o.logf("can't cache token: %v", err)
 	}
 
 	rc := &Client{
-		client: client,
-		server: server,
-		status: status,
+		client:  client,
+		server:  server,
+		status:  status,
+		options: o,
+		root:    &rootCache{},
 	}
 
-	root, err := rc.Root()
+	_, err = rc.Root()
 	if err != nil {
 		return nil, err
 	}
 
-	rc.root = root
-
 	return rc, nil
 }
 
@@ -189,6 +271,56 @@ func (c *Client) Cluster() (string, error) {
 	return c.Get("/controller/cluster")
 }
 
+// Node returns a copy of the client scoped to a single cluster node. Status,
+// diagnostics and listings then report only the view of this node.
+func (c *Client) Node(id string) *Client {
+	rc := *c
+	rc.node = id
+	return &rc
+}
+
+func (c *Client) NodeID() string {
+	return c.node
+}
+
+// DefaultGroup returns the id of the default process group of the config
+// context, which may be set by id or path, or the id of the root group.
+func (c *Client) DefaultGroup() (string, error) {
+	if len(c.group) == 0 {
+		root, err := c.Root()
+		if err != nil {
+			return "", err
+		}
+
+		return root.ID, nil
+	}
+
+	if isUUID(c.group) {
+		return c.group, nil
+	}
+
+	group, err := c.FindByPath(c.group, ProcessGroup)
+	if err != nil {
+		return "", err
+	}
+
+	return group.ID, nil
+}
+
+func (c *Client) nodeQuery(nodewise bool) []string {
+	query := []string{}
+
+	if nodewise {
+		query = append(query, "nodewise=true")
+	}
+
+	if len(c.node) > 0 {
+		query = append(query, "clusterNodeId="+c.node)
+	}
+
+	return query
+}
+
 func (c *Client) Get(path string, query ...string) (string, error) {
 	return c.CallAPI(Get, path, nil, query...)
 }
@@ -206,6 +338,10 @@ func (c *Client) Delete(path string, query ...string) (string, error) {
 }
 
 func (c *Client) CallAPI(method Method, path string, data []byte, query ...string) (string, error) {
+	return c.Call(method, c.URL(path, query...), data)
+}
+
+func (c *Client) URL(path string, query ...string) *url.URL {
 	u := *c.server
 	u.Path = "/nifi-api" + path
 
@@ -213,7 +349,7 @@ func (c *Client) CallAPI(method Method, path string, data []byte, query ...strin
 		u.RawQuery = url.PathEscape(strings.Join(query, "&"))
 	}
 
-	return c.Call(method, &u, data)
+	return &u
 }
 
 func (c *Client) Call(method Method, url *url.URL, data []byte) (string, error) {
@@ -223,38 +359,124 @@ func (c *Client) Call(method Method, url *url.URL, data []byte) (string, error)
 		reader = strings.NewReader(string(data))
 	}
 
-	request, err := c.status.NewRequest(string(method), url.String(), reader)
+	response, err := c.Do(method, url, reader, "application/json")
 	if err != nil {
 		return "", err
 	}
 
-	request.Header.Add("Content-Type", "application/json")
+	return readJSON(response)
+}
 
-	response, err := c.client.Do(request)
+func (c *Client) CallStream(method Method, url *url.URL, body io.Reader, contentType string, w io.Writer) error {
+	response, err := c.Do(method, url, body, contentType)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
+	defer response.Body.Close()
+
+	_, err = io.Copy(w, response.Body)
+	return err
+}
+
+func (c *Client) CallMultipart(method Method, url *url.URL, fields map[string]string, fileField string, fileName string, file io.Reader) (string, error) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		err := writeMultipart(form, fields, fileField, fileName, file)
+		if err == nil {
+			err = form.Close()
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	response, err := c.Do(method, url, reader, form.FormDataContentType())
+	reader.Close()
+
 	if err != nil {
 		return "", err
 	}
 
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return "", fmt.Errorf("%s: %s", response.Status, body)
+	return readJSON(response)
+}
+
+func writeMultipart(form *multipart.Writer, fields map[string]string, fileField string, fileName string, file io.Reader) error {
+	for k, v := range fields {
+		err := form.WriteField(k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile(fileField, fileName)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, file)
+	return err
+}
+
+func readJSON(response *http.Response) (string, error) {
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
 	}
 
 	contentType := response.Header.Get("Content-Type")
-	if contentType != "application/json" {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/json" {
 		return "", fmt.Errorf("unexpected content type: %v\n%v", contentType, string(body))
 	}
 
 	return string(body), nil
 }
 
+// Do sends an authenticated request and returns the response, if the status
+// code is 2xx. The caller has to close the body.
+func (c *Client) Do(method Method, url *url.URL, body io.Reader, contentType string) (*http.Response, error) {
+	request, err := c.status.NewRequest(string(method), url.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contentType) > 0 {
+		request.Header.Add("Content-Type", contentType)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &HTTPError{
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       string(body),
+		}
+	}
+
+	return response, nil
+}
+
 func (c *Client) Root() (*Component, error) {
-	if c.root != nil {
-		return c.root, nil
+	c.root.mutex.Lock()
+	defer c.root.mutex.Unlock()
+
+	if c.root.component != nil {
+		return c.root.component, nil
 	}
 
 	data, err := c.CallAPI(Get, "/process-groups/root", nil)
@@ -283,12 +505,14 @@ func (c *Client) Root() (*Component, error) {
 		return nil, fmt.Errorf("root name not found")
 	}
 
-	return &Component{
+	c.root.component = &Component{
 		ID:         id,
 		Name:       name,
 		Path:       "",
 		Type:       ProcessGroup,
 		TypeName:   ProcessGroupTitle,
 		Attributes: cp,
-	}, nil
+	}
+
+	return c.root.component, nil
 }
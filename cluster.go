@@ -0,0 +1,281 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CONNECTING    = "CONNECTING"
+	CONNECTED     = "CONNECTED"
+	DISCONNECTING = "DISCONNECTING"
+	DISCONNECTED  = "DISCONNECTED"
+	OFFLOADING    = "OFFLOADING"
+	OFFLOADED     = "OFFLOADED"
+)
+
+const (
+	PrimaryNode     = "Primary Node"
+	CoordinatorNode = "Cluster Coordinator"
+)
+
+var (
+	ErrTimeout = fmt.Errorf("timeout")
+
+	PollInterval = 2 * time.Second
+)
+
+type ClusterNode struct {
+	ID                string    `json:"nodeId"`
+	Address           string    `json:"address"`
+	ApiPort           int       `json:"apiPort"`
+	Status            string    `json:"status"`
+	Roles             []string  `json:"roles"`
+	Heartbeat         time.Time `json:"heartbeat"`
+	NodeStartTime     time.Time `json:"nodeStartTime"`
+	Queued            string    `json:"queued"`
+	FlowFilesQueued   int       `json:"flowFilesQueued"`
+	BytesQueued       int64     `json:"flowFileBytes"`
+	ActiveThreadCount int       `json:"activeThreadCount"`
+}
+
+func (n *ClusterNode) String() string {
+	return fmt.Sprintf("%v:%v (%v)", n.Address, n.ApiPort, n.Status)
+}
+
+func (n *ClusterNode) HasRole(role string) bool {
+	for _, r := range n.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *ClusterNode) UnmarshalJSON(data []byte) error {
+	type plain ClusterNode
+
+	var raw struct {
+		plain
+		Heartbeat     string `json:"heartbeat"`
+		NodeStartTime string `json:"nodeStartTime"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*n = ClusterNode(raw.plain)
+	n.Heartbeat = parseNiFiTime(raw.Heartbeat)
+	n.NodeStartTime = parseNiFiTime(raw.NodeStartTime)
+
+	parts := strings.SplitN(n.Queued, "/", 2)
+
+	if n.FlowFilesQueued == 0 && len(n.Queued) > 0 {
+		count := strings.TrimSpace(parts[0])
+		val, err := strconv.Atoi(strings.Replace(count, ",", "", -1))
+		if err == nil {
+			n.FlowFilesQueued = val
+		}
+	}
+
+	if n.BytesQueued == 0 && len(parts) == 2 {
+		n.BytesQueued = parseDataSize(parts[1])
+	}
+
+	return nil
+}
+
+var dataSizeUnits = map[string]float64{
+	"bytes": 1,
+	"kb":    1 << 10,
+	"mb":    1 << 20,
+	"gb":    1 << 30,
+	"tb":    1 << 40,
+}
+
+// parseDataSize parses sizes formatted by NiFi like "1.5 MB" or "12 bytes".
+func parseDataSize(txt string) int64 {
+	fields := strings.Fields(strings.Replace(txt, ",", "", -1))
+	if len(fields) != 2 {
+		return 0
+	}
+
+	val, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+
+	return int64(val * dataSizeUnits[strings.ToLower(fields[1])])
+}
+
+func parseNiFiTime(txt string) time.Time {
+	for _, layout := range []string{"01/02/2006 15:04:05 MST", "01/02/2006 15:04:05.000 MST", time.RFC3339} {
+		t, err := time.Parse(layout, txt)
+		if err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Clustered checks with the cluster summary, if the server is part of a
+// cluster. Cluster requests of a standalone server fail with 409 like the
+// ones of a disconnected node.
+func (c *Client) Clustered() (bool, error) {
+	response, err := c.Get("/flow/cluster/summary")
+	if err != nil {
+		return false, err
+	}
+
+	var output struct {
+		ClusterSummary struct {
+			Clustered bool `json:"clustered"`
+		} `json:"clusterSummary"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return false, err
+	}
+
+	return output.ClusterSummary.Clustered, nil
+}
+
+func (c *Client) ClusterNodes() ([]*ClusterNode, error) {
+	response, err := c.Cluster()
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Cluster struct {
+			Nodes []*ClusterNode `json:"nodes"`
+		} `json:"cluster"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Cluster.Nodes, nil
+}
+
+func (c *Client) GetClusterNode(id string) (*ClusterNode, error) {
+	response, err := c.Get("/controller/cluster/nodes/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClusterNode(response)
+}
+
+func (c *Client) DisconnectNode(id string, timeout time.Duration) (*ClusterNode, error) {
+	return c.setNodeStatus(id, DISCONNECTING, DISCONNECTED, timeout)
+}
+
+func (c *Client) ConnectNode(id string, timeout time.Duration) (*ClusterNode, error) {
+	return c.setNodeStatus(id, CONNECTING, CONNECTED, timeout)
+}
+
+func (c *Client) OffloadNode(id string, timeout time.Duration) (*ClusterNode, error) {
+	return c.setNodeStatus(id, OFFLOADING, OFFLOADED, timeout)
+}
+
+func (c *Client) DeleteNode(id string) error {
+	_, err := c.Delete("/controller/cluster/nodes/" + id)
+	return err
+}
+
+// WaitForNode polls the node until it reaches the status or the timeout
+// expires. A timeout of 0 waits forever.
+func (c *Client) WaitForNode(id string, status string, timeout time.Duration) (*ClusterNode, error) {
+	start := time.Now()
+
+	for {
+		node, err := c.GetClusterNode(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if node.Status == status {
+			return node, nil
+		}
+
+		if timeout > 0 && time.Since(start) > timeout {
+			return node, fmt.Errorf("node %v is %v instead of %v: %w", node, node.Status, status, ErrTimeout)
+		}
+
+		time.Sleep(PollInterval)
+	}
+}
+
+func (c *Client) setNodeStatus(id string, status string, expected string, timeout time.Duration) (*ClusterNode, error) {
+	body := map[string]interface{}{
+		"node": map[string]interface{}{
+			"nodeId": id,
+			"status": status,
+		},
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.Put("/controller/cluster/nodes/"+id, data)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := newClusterNode(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Status == expected {
+		return node, nil
+	}
+
+	return c.WaitForNode(id, expected, timeout)
+}
+
+func newClusterNode(response string) (*ClusterNode, error) {
+	var output struct {
+		Node *ClusterNode `json:"node"`
+	}
+
+	err := json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Node == nil {
+		return nil, fmt.Errorf("cluster node not found")
+	}
+
+	return output.Node, nil
+}
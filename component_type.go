@@ -67,10 +67,30 @@ func (t NiFiType) String() string {
 	return "unknown"
 }
 
+func (t NiFiType) Resource() string {
+	switch t {
+	case ProcessGroup:
+		return "process-groups"
+	case Processor:
+		return "processors"
+	case RemoteProcessGroup:
+		return "remote-process-groups"
+	case Connection:
+		return "connections"
+	case InputPort:
+		return "input-ports"
+	case OutputPort:
+		return "output-ports"
+	}
+
+	return ""
+}
+
 type Component struct {
 	ID         string                 `json:"id"`
 	Name       string                 `json:"name"`
 	Path       string                 `json:"path"`
+	ParentID   string                 `json:"parentId,omitempty"`
 	Type       NiFiType               `json:"-"`
 	TypeName   string                 `json:"type"`
 	Attributes map[string]interface{} `json:"-"`
@@ -56,50 +56,191 @@ func (c *Client) AllWith(ids []string, types NiFiType, recursive bool, filter Co
 	return result, nil
 }
 
-func (c *Client) all(id string, types NiFiType, recursive bool, filter ComponentFilter) ([]*Component, error) {
-	data, err := c.Get(fmt.Sprintf("/flow/process-groups/%v/status", id),
-		"recursive="+strconv.FormatBool(recursive),
-	)
+// FindByPath returns the component with the path, which is either the full
+// path starting with the root process group name or relative to the root
+// process group.
+func (c *Client) FindByPath(path string, types NiFiType) (*Component, error) {
+	if len(strings.TrimSuffix(path, "/")) == 0 && types&ProcessGroup > 0 {
+		return c.Root()
+	}
 
+	index, err := c.pathIndex(types)
 	if err != nil {
 		return nil, err
 	}
 
-	var input map[string]interface{}
-	err = json.Unmarshal([]byte(data), &input)
+	return index.find(path)
+}
+
+// pathIndex maps the full paths of the components to the components, so
+// several paths can be resolved with a single walk of the flow.
+type pathIndex struct {
+	root       *Component
+	types      NiFiType
+	components map[string][]*Component
+}
+
+func (c *Client) pathIndex(types NiFiType) (*pathIndex, error) {
+	root, err := c.Root()
 	if err != nil {
 		return nil, err
 	}
 
-	val, ok := input["processGroupStatus"]
-	if !ok {
-		return nil, ErrInvalidFormat
+	list, err := c.All([]string{root.ID}, types, true)
+	if err != nil {
+		return nil, err
 	}
 
-	input, ok = val.(map[string]interface{})
-	if !ok {
-		return nil, ErrInvalidFormat
+	index := &pathIndex{
+		root:       root,
+		types:      types,
+		components: map[string][]*Component{},
 	}
 
-	val, ok = input["aggregateSnapshot"]
-	if !ok {
-		return nil, ErrInvalidFormat
+	for _, component := range list {
+		full := component.Path + "/" + component.Name
+		index.components[full] = append(index.components[full], component)
+	}
+
+	return index, nil
+}
+
+// find returns the component of the path, which may start with the name of
+// the root group or omit it.
+func (i *pathIndex) find(path string) (*Component, error) {
+	path = strings.TrimSuffix(path, "/")
+	if len(path) == 0 && i.types&ProcessGroup > 0 {
+		return i.root, nil
+	}
+
+	list := append(append([]*Component{}, i.components[path]...), i.components["/"+i.root.Name+path]...)
+
+	switch len(list) {
+	case 0:
+		return nil, fmt.Errorf("%v: %w", path, ErrNotFound)
+	case 1:
+		return list[0], nil
+	default:
+		return nil, fmt.Errorf("%v: %w", path, ErrAmbiguous)
 	}
+}
 
-	input, ok = val.(map[string]interface{})
+func (c *Client) all(id string, types NiFiType, recursive bool, filter ComponentFilter) ([]*Component, error) {
+	input, err := c.statusSnapshot(id, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.loop(0, "processGroupStatusSnapshots", "", "", input, types, recursive, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// AllByNode returns the components per cluster node id instead of the
+// aggregated snapshot.
+func (c *Client) AllByNode(ids []string, types NiFiType, recursive bool) (map[string][]*Component, error) {
+	result := map[string][]*Component{}
+
+	for _, id := range ids {
+		status, err := c.processGroupStatus(id, recursive, true)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots, ok := status["nodeSnapshots"].([]interface{})
+		if !ok {
+			return nil, ErrInvalidFormat
+		}
+
+		for _, s := range snapshots {
+			node, ok := s.(map[string]interface{})
+			if !ok {
+				return nil, ErrInvalidFormat
+			}
+
+			nodeId, _ := node["nodeId"].(string)
+			if len(c.node) > 0 && nodeId != c.node {
+				continue
+			}
+
+			snapshot, ok := node["statusSnapshot"].(map[string]interface{})
+			if !ok {
+				return nil, ErrInvalidFormat
+			}
+
+			list, err := c.loop(0, "processGroupStatusSnapshots", "", "", snapshot, types, recursive, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			result[nodeId] = append(result[nodeId], list...)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) statusSnapshot(id string, recursive bool) (map[string]interface{}, error) {
+	nodewise := len(c.node) > 0
+
+	status, err := c.processGroupStatus(id, recursive, nodewise)
+	if err != nil {
+		return nil, err
+	}
+
+	// The aggregate is the status of the node, if it answered without
+	// snapshots of the other nodes.
+	snapshots, _ := status["nodeSnapshots"].([]interface{})
+	if nodewise && len(snapshots) > 0 {
+		for _, s := range snapshots {
+			node, ok := s.(map[string]interface{})
+			if ok && node["nodeId"] == c.node {
+				input, ok := node["statusSnapshot"].(map[string]interface{})
+				if !ok {
+					return nil, ErrInvalidFormat
+				}
+
+				return input, nil
+			}
+		}
+
+		return nil, fmt.Errorf("status of node %v: %w", c.node, ErrNotFound)
+	}
+
+	input, ok := status["aggregateSnapshot"].(map[string]interface{})
 	if !ok {
 		return nil, ErrInvalidFormat
 	}
 
-	list, err := c.loop(0, "processGroupStatusSnapshots", "", input, types, recursive, filter)
+	return input, nil
+}
+
+func (c *Client) processGroupStatus(id string, recursive bool, nodewise bool) (map[string]interface{}, error) {
+	query := append([]string{"recursive=" + strconv.FormatBool(recursive)}, c.nodeQuery(nodewise)...)
+
+	data, err := c.Get(fmt.Sprintf("/flow/process-groups/%v/status", id), query...)
 	if err != nil {
 		return nil, err
 	}
 
-	return list, nil
+	var input map[string]interface{}
+	err = json.Unmarshal([]byte(data), &input)
+	if err != nil {
+		return nil, err
+	}
+
+	status, ok := input["processGroupStatus"].(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidFormat
+	}
+
+	return status, nil
 }
 
-func (c *Client) loop(level int, name string, path string, o map[string]interface{}, types NiFiType, recursive bool, filter ComponentFilter) ([]*Component, error) {
+func (c *Client) loop(level int, name string, path string, parent string, o map[string]interface{}, types NiFiType, recursive bool, filter ComponentFilter) ([]*Component, error) {
 	result := []*Component{}
 
 	if len(o) == 0 {
@@ -111,8 +252,21 @@ func (c *Client) loop(level int, name string, path string, o map[string]interfac
 
 	component := NewComponent(name, path, o)
 	if component != nil {
-		if len(path) == 0 && component.ID != c.root.ID {
-			path += "."
+		component.ParentID = parent
+
+		if component.Type == ProcessGroup {
+			parent = component.ID
+		}
+
+		if len(path) == 0 {
+			root, err := c.Root()
+			if err != nil {
+				return nil, err
+			}
+
+			if component.ID != root.ID {
+				path += "."
+			}
 		}
 
 		path = path + "/" + component.Name
@@ -145,7 +299,7 @@ func (c *Client) loop(level int, name string, path string, o map[string]interfac
 							return nil, err
 						}
 
-						l, err := c.loop(level+1, k, path, snapshot, types, recursive, filter)
+						l, err := c.loop(level+1, k, path, parent, snapshot, types, recursive, filter)
 						if err != nil {
 							return nil, err
 						}
@@ -0,0 +1,392 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is used, if neither a path nor NIFI_CONFIG is set.
+const DefaultConfigFile = "~/.nifi/config.yaml"
+
+const (
+	AuthAuto        = "auto"
+	AuthPassword    = "password"
+	AuthCertificate = "certificate"
+	AuthOIDC        = "oidc"
+	AuthTokenFile   = "token-file"
+	AuthKerberos    = "kerberos"
+)
+
+var (
+	ErrInsecureConfig = fmt.Errorf("config file with secrets is readable by group or others")
+)
+
+// OIDCConfig describes the client credentials of the IdP. The CA is only used
+// for the token endpoint, which trusts the system roots by default.
+type OIDCConfig struct {
+	TokenURL         string   `yaml:"token-url"`
+	ClientID         string   `yaml:"client-id"`
+	ClientSecret     string   `yaml:"client-secret,omitempty"`
+	ClientSecretFile string   `yaml:"client-secret-file,omitempty"`
+	Scopes           []string `yaml:"scopes,omitempty"`
+	CA               string   `yaml:"ca,omitempty"`
+}
+
+// ClientContext holds the connection settings of a cluster. The client
+// certificate is either a PKCS#12 file or a PEM certificate and key. A token
+// cache of "none" disables the cache. Secrets can be read from the files of
+// the *-file settings instead of storing them in the config.
+type ClientContext struct {
+	Name            string          `yaml:"name"`
+	Server          string          `yaml:"server"`
+	Auth            string          `yaml:"auth,omitempty"`
+	CA              string          `yaml:"ca,omitempty"`
+	Insecure        bool            `yaml:"insecure,omitempty"`
+	Username        string          `yaml:"username,omitempty"`
+	Password        string          `yaml:"password,omitempty"`
+	PasswordFile    string          `yaml:"password-file,omitempty"`
+	PKCS12          string          `yaml:"pkcs12,omitempty"`
+	PKCS12Pass      string          `yaml:"pkcs12-password,omitempty"`
+	PKCS12PassFile  string          `yaml:"pkcs12-password-file,omitempty"`
+	ClientCert      string          `yaml:"client-cert,omitempty"`
+	ClientKey       string          `yaml:"client-key,omitempty"`
+	KeyPassword     string          `yaml:"key-password,omitempty"`
+	KeyPasswordFile string          `yaml:"key-password-file,omitempty"`
+	TokenCache      string          `yaml:"token-cache,omitempty"`
+	TokenFile       string          `yaml:"token-file,omitempty"`
+	OIDC            *OIDCConfig     `yaml:"oidc,omitempty"`
+	Kerberos        *KerberosConfig `yaml:"kerberos,omitempty"`
+	RootGroup       string          `yaml:"root-group,omitempty"`
+	Proxy           string          `yaml:"proxy,omitempty"`
+}
+
+type ClientConfig struct {
+	CurrentContext string           `yaml:"current-context"`
+	Contexts       []*ClientContext `yaml:"contexts"`
+}
+
+// LoadClientConfig reads the config file. A file with secrets is refused,
+// if it's readable by group or others.
+func LoadClientConfig(path string) (*ClientConfig, error) {
+	path = expandPath(path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ClientConfig{}
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.hasSecrets() && runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("%v (%v): %w", path, info.Mode().Perm(), ErrInsecureConfig)
+		}
+	}
+
+	return config, nil
+}
+
+func (c *ClientConfig) hasSecrets() bool {
+	for _, ctx := range c.Contexts {
+		if len(ctx.Password) > 0 || len(ctx.PKCS12Pass) > 0 || len(ctx.KeyPassword) > 0 {
+			return true
+		}
+
+		if ctx.OIDC != nil && len(ctx.OIDC.ClientSecret) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Context returns a copy of the context or the current context, if the name
+// is empty. The copy can be changed without changing the config.
+func (c *ClientConfig) Context(name string) (*ClientContext, error) {
+	if len(name) == 0 {
+		name = c.CurrentContext
+	}
+
+	if len(name) == 0 && len(c.Contexts) == 1 {
+		name = c.Contexts[0].Name
+	}
+
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			copy := *ctx
+
+			if ctx.OIDC != nil {
+				oidc := *ctx.OIDC
+				copy.OIDC = &oidc
+			}
+
+			if ctx.Kerberos != nil {
+				kerberos := *ctx.Kerberos
+				copy.Kerberos = &kerberos
+			}
+
+			return &copy, nil
+		}
+	}
+
+	return nil, fmt.Errorf("context %v: %w", name, ErrNotFound)
+}
+
+// NewClientFromConfig creates a client for a context of the config file. The
+// path defaults to NIFI_CONFIG or DefaultConfigFile and the context to
+// NIFI_CONTEXT or the current context. The settings can be overridden by the
+// environment variables NIFI_SERVER, NIFI_AUTH, NIFI_CA, NIFI_INSECURE,
+// NIFI_USERNAME, NIFI_PASSWORD, NIFI_PKCS12_PASSWORD, NIFI_KEY_PASSWORD,
+// NIFI_OIDC_CLIENT_SECRET, NIFI_TOKEN_CACHE, NIFI_ROOT_GROUP and NIFI_PROXY.
+func NewClientFromConfig(path string, contextName string, options ...Option) (*Client, error) {
+	if len(path) == 0 {
+		path = os.Getenv("NIFI_CONFIG")
+	}
+
+	if len(path) == 0 {
+		path = DefaultConfigFile
+	}
+
+	if len(contextName) == 0 {
+		contextName = os.Getenv("NIFI_CONTEXT")
+	}
+
+	config, err := LoadClientConfig(path)
+	if os.IsNotExist(err) && len(os.Getenv("NIFI_SERVER")) > 0 {
+		config = &ClientConfig{
+			Contexts: []*ClientContext{{Name: contextName}},
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	ctx, err := config.Context(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.applyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.NewClient(options...)
+}
+
+func (ctx *ClientContext) applyEnv() error {
+	env := map[string]*string{
+		"NIFI_SERVER":          &ctx.Server,
+		"NIFI_AUTH":            &ctx.Auth,
+		"NIFI_CA":              &ctx.CA,
+		"NIFI_USERNAME":        &ctx.Username,
+		"NIFI_PASSWORD":        &ctx.Password,
+		"NIFI_PKCS12_PASSWORD": &ctx.PKCS12Pass,
+		"NIFI_KEY_PASSWORD":    &ctx.KeyPassword,
+		"NIFI_TOKEN_CACHE":     &ctx.TokenCache,
+		"NIFI_ROOT_GROUP":      &ctx.RootGroup,
+		"NIFI_PROXY":           &ctx.Proxy,
+	}
+
+	if ctx.OIDC != nil {
+		env["NIFI_OIDC_CLIENT_SECRET"] = &ctx.OIDC.ClientSecret
+	}
+
+	for name, field := range env {
+		if val, ok := os.LookupEnv(name); ok {
+			*field = val
+		}
+	}
+
+	if val, ok := os.LookupEnv("NIFI_INSECURE"); ok {
+		insecure, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("NIFI_INSECURE: %w", err)
+		}
+
+		ctx.Insecure = insecure
+	}
+
+	return nil
+}
+
+// readSecrets reads the secrets of the *-file settings, which aren't set
+// directly or by the environment.
+func (ctx *ClientContext) readSecrets() error {
+	secrets := map[*string]string{
+		&ctx.Password:    ctx.PasswordFile,
+		&ctx.PKCS12Pass:  ctx.PKCS12PassFile,
+		&ctx.KeyPassword: ctx.KeyPasswordFile,
+	}
+
+	if ctx.OIDC != nil {
+		secrets[&ctx.OIDC.ClientSecret] = ctx.OIDC.ClientSecretFile
+	}
+
+	for field, file := range secrets {
+		if len(*field) > 0 || len(file) == 0 {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(expandPath(file))
+		if err != nil {
+			return err
+		}
+
+		*field = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return nil
+}
+
+// Options returns the client options of the context.
+func (ctx *ClientContext) Options() ([]Option, error) {
+	err := ctx.readSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []Option{
+		WithCA(expandPath(ctx.CA)),
+		WithInsecureSkipVerify(ctx.Insecure),
+	}
+
+	if len(ctx.Proxy) > 0 {
+		proxy, err := url.Parse(ctx.Proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, WithProxyURL(proxy))
+	}
+
+	switch ctx.TokenCache {
+	case "":
+	case "none":
+		options = append(options, WithoutTokenCache())
+	default:
+		options = append(options, WithTokenStore(NewFileTokenStore(expandPath(ctx.TokenCache))))
+	}
+
+	if len(ctx.PKCS12) > 0 {
+		options = append(options, WithPKCS12(expandPath(ctx.PKCS12), ctx.PKCS12Pass))
+	}
+
+	if len(ctx.ClientCert) > 0 {
+		options = append(options, WithPEMKeyPair(expandPath(ctx.ClientCert), expandPath(ctx.ClientKey), ctx.KeyPassword))
+	}
+
+	if ctx.Kerberos != nil {
+		options = append(options, WithKerberos(ctx.Kerberos))
+	}
+
+	if ctx.OIDC != nil {
+		var client *http.Client
+		if len(ctx.OIDC.CA) > 0 {
+			client, err = NewTokenClient(expandPath(ctx.OIDC.CA))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		options = append(options, WithTokenSource(NewClientCredentials(client, ctx.OIDC.TokenURL, ctx.OIDC.ClientID, ctx.OIDC.ClientSecret, ctx.OIDC.Scopes...)))
+	} else if len(ctx.TokenFile) > 0 {
+		options = append(options, WithTokenSource(NewFileTokenSource(expandPath(ctx.TokenFile))))
+	}
+
+	return options, nil
+}
+
+// NewClient logs in or connects with the auth method of the context. Without
+// a method, it's detected by LoginAuto.
+func (ctx *ClientContext) NewClient(options ...Option) (*Client, error) {
+	if len(ctx.Server) == 0 {
+		return nil, fmt.Errorf("context %v: server is missing", ctx.Name)
+	}
+
+	server, err := url.Parse(ctx.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := ctx.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	options = append(defaults, options...)
+
+	var client *Client
+
+	switch ctx.Auth {
+	case "", AuthAuto:
+		client, err = LoginAuto(server, ctx.Username, ctx.Password, options...)
+	case AuthPassword:
+		client, err = LoginWithOptions(server, ctx.Username, ctx.Password, options...)
+	case AuthCertificate, AuthOIDC, AuthTokenFile:
+		client, err = ConnectWithOptions(server, options...)
+	case AuthKerberos:
+		kerberos := ctx.Kerberos
+		if kerberos == nil {
+			kerberos = &KerberosConfig{}
+		}
+
+		client, err = LoginKerberos(server, kerberos, options...)
+	default:
+		return nil, fmt.Errorf("context %v: %v: %w", ctx.Name, ctx.Auth, ErrUnsupportedLogin)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	client.group = ctx.RootGroup
+
+	return client, nil
+}
+
+func expandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
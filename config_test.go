@@ -0,0 +1,117 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string, mode os.FileMode) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := ioutil.WriteFile(path, []byte(content), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chmod(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadClientConfigWithReadableSecrets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes aren't checked on windows")
+	}
+
+	config := `
+contexts:
+  - name: dev
+    server: https://nifi.example.com:8443
+    password: secret
+`
+
+	_, err := LoadClientConfig(writeConfig(t, config, 0644))
+	if !errors.Is(err, ErrInsecureConfig) {
+		t.Errorf("err = %v, want %v", err, ErrInsecureConfig)
+	}
+
+	_, err = LoadClientConfig(writeConfig(t, config, 0600))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientContextSecrets(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, secret := range map[string]string{"password": "password\n", "client-secret": "client-secret\n"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(secret), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := LoadClientConfig(writeConfig(t, `
+contexts:
+  - name: dev
+    server: https://nifi.example.com:8443
+    password-file: `+filepath.Join(dir, "password")+`
+    key-password-file: `+filepath.Join(dir, "missing")+`
+    oidc:
+      token-url: https://idp.example.com/token
+      client-id: nifi
+      client-secret-file: `+filepath.Join(dir, "client-secret")+`
+`, 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := config.Context("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("NIFI_KEY_PASSWORD", "key-password")
+	defer os.Unsetenv("NIFI_KEY_PASSWORD")
+
+	err = ctx.applyEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.readSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Password != "password" || ctx.KeyPassword != "key-password" || ctx.OIDC.ClientSecret != "client-secret" {
+		t.Errorf("unexpected secrets: %v %v %v", ctx.Password, ctx.KeyPassword, ctx.OIDC.ClientSecret)
+	}
+
+	if len(config.Contexts[0].OIDC.ClientSecret) > 0 {
+		t.Error("secret written to the config")
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StorageUsage struct {
+	Identifier string `json:"identifier"`
+	FreeBytes  int64  `json:"freeSpaceBytes"`
+	TotalBytes int64  `json:"totalSpaceBytes"`
+	UsedBytes  int64  `json:"usedSpaceBytes"`
+}
+
+func (u *StorageUsage) Utilization() float64 {
+	return percent(u.UsedBytes, u.TotalBytes)
+}
+
+type GarbageCollection struct {
+	Name             string `json:"name"`
+	CollectionCount  int64  `json:"collectionCount"`
+	CollectionMillis int64  `json:"collectionMillis"`
+}
+
+type DiagnosticsSnapshot struct {
+	TotalHeapBytes       int64               `json:"totalHeapBytes"`
+	UsedHeapBytes        int64               `json:"usedHeapBytes"`
+	FreeHeapBytes        int64               `json:"freeHeapBytes"`
+	MaxHeapBytes         int64               `json:"maxHeapBytes"`
+	TotalNonHeapBytes    int64               `json:"totalNonHeapBytes"`
+	UsedNonHeapBytes     int64               `json:"usedNonHeapBytes"`
+	FreeNonHeapBytes     int64               `json:"freeNonHeapBytes"`
+	MaxNonHeapBytes      int64               `json:"maxNonHeapBytes"`
+	AvailableProcessors  int                 `json:"availableProcessors"`
+	ProcessorLoadAverage float64             `json:"processorLoadAverage"`
+	TotalThreads         int                 `json:"totalThreads"`
+	DaemonThreads        int                 `json:"daemonThreads"`
+	Uptime               string              `json:"uptime"`
+	FlowFileRepository   *StorageUsage       `json:"flowFileRepositoryStorageUsage"`
+	ContentRepositories  []*StorageUsage     `json:"contentRepositoryStorageUsage"`
+	ProvenanceRepository []*StorageUsage     `json:"provenanceRepositoryStorageUsage"`
+	GarbageCollection    []GarbageCollection `json:"garbageCollection"`
+}
+
+func (s *DiagnosticsSnapshot) HeapUtilization() float64 {
+	max := s.MaxHeapBytes
+	if max <= 0 {
+		max = s.TotalHeapBytes
+	}
+
+	return percent(s.UsedHeapBytes, max)
+}
+
+// UptimeDuration parses the uptime, which NiFi reports as HH:mm:ss.SSS.
+func (s *DiagnosticsSnapshot) UptimeDuration() time.Duration {
+	parts := strings.Split(s.Uptime, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0
+	}
+
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+
+	sec, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second))
+}
+
+func (s *DiagnosticsSnapshot) Repositories() map[string]*StorageUsage {
+	result := map[string]*StorageUsage{}
+
+	if s.FlowFileRepository != nil {
+		result["flowfile"] = s.FlowFileRepository
+	}
+
+	for _, r := range s.ContentRepositories {
+		result["content/"+r.Identifier] = r
+	}
+
+	for _, r := range s.ProvenanceRepository {
+		result["provenance/"+r.Identifier] = r
+	}
+
+	return result
+}
+
+type DiagnosticsThresholds struct {
+	Heap       float64
+	Repository float64
+}
+
+type DiagnosticsViolation struct {
+	Node      string  `json:"node"`
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (v DiagnosticsViolation) String() string {
+	if len(v.Node) > 0 {
+		return fmt.Sprintf("%v: %v %.1f%% > %.1f%%", v.Node, v.Name, v.Value, v.Threshold)
+	}
+
+	return fmt.Sprintf("%v %.1f%% > %.1f%%", v.Name, v.Value, v.Threshold)
+}
+
+type byNodeAndName []DiagnosticsViolation
+
+func (a byNodeAndName) Len() int      { return len(a) }
+func (a byNodeAndName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byNodeAndName) Less(i, j int) bool {
+	if a[i].Node == a[j].Node {
+		return a[i].Name < a[j].Name
+	}
+
+	return a[i].Node < a[j].Node
+}
+
+// Check returns all values above the thresholds in percent sorted by name. A
+// threshold of 0 disables the check.
+func (s *DiagnosticsSnapshot) Check(node string, t DiagnosticsThresholds) []DiagnosticsViolation {
+	result := []DiagnosticsViolation{}
+
+	if t.Heap > 0 {
+		if val := s.HeapUtilization(); val > t.Heap {
+			result = append(result, DiagnosticsViolation{Node: node, Name: "heap", Value: val, Threshold: t.Heap})
+		}
+	}
+
+	if t.Repository > 0 {
+		for name, r := range s.Repositories() {
+			if val := r.Utilization(); val > t.Repository {
+				result = append(result, DiagnosticsViolation{Node: node, Name: name, Value: val, Threshold: t.Repository})
+			}
+		}
+	}
+
+	sort.Sort(byNodeAndName(result))
+
+	return result
+}
+
+type NodeDiagnosticsSnapshot struct {
+	NodeID   string               `json:"nodeId"`
+	Address  string               `json:"address"`
+	ApiPort  int                  `json:"apiPort"`
+	Snapshot *DiagnosticsSnapshot `json:"snapshot"`
+}
+
+type SystemDiagnostics struct {
+	Aggregate *DiagnosticsSnapshot       `json:"aggregateSnapshot"`
+	Nodes     []*NodeDiagnosticsSnapshot `json:"nodeSnapshots"`
+}
+
+// Check checks the per node snapshots or the aggregate snapshot, if there are
+// no node snapshots. The violations are sorted by node and name.
+func (s *SystemDiagnostics) Check(t DiagnosticsThresholds) []DiagnosticsViolation {
+	if len(s.Nodes) == 0 {
+		if s.Aggregate == nil {
+			return []DiagnosticsViolation{}
+		}
+
+		return s.Aggregate.Check("", t)
+	}
+
+	result := []DiagnosticsViolation{}
+	for _, n := range s.Nodes {
+		if n.Snapshot != nil {
+			result = append(result, n.Snapshot.Check(fmt.Sprintf("%v:%v", n.Address, n.ApiPort), t)...)
+		}
+	}
+
+	sort.Sort(byNodeAndName(result))
+
+	return result
+}
+
+func (c *Client) SystemDiagnostics(nodewise bool) (*SystemDiagnostics, error) {
+	response, err := c.Get("/system-diagnostics", c.nodeQuery(nodewise)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		SystemDiagnostics *SystemDiagnostics `json:"systemDiagnostics"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.SystemDiagnostics == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	return output.SystemDiagnostics, nil
+}
+
+func percent(val int64, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	return float64(val) * 100 / float64(total)
+}
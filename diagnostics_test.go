@@ -0,0 +1,65 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemDiagnosticsCheckOrder(t *testing.T) {
+	full := func(id string) *StorageUsage {
+		return &StorageUsage{Identifier: id, UsedBytes: 95, TotalBytes: 100}
+	}
+
+	snapshot := func() *DiagnosticsSnapshot {
+		return &DiagnosticsSnapshot{
+			UsedHeapBytes:        95,
+			MaxHeapBytes:         100,
+			FlowFileRepository:   full(""),
+			ContentRepositories:  []*StorageUsage{full("c2"), full("c1"), full("c3")},
+			ProvenanceRepository: []*StorageUsage{full("p1"), full("p2")},
+		}
+	}
+
+	diagnostics := &SystemDiagnostics{
+		Nodes: []*NodeDiagnosticsSnapshot{
+			{Address: "node-b", ApiPort: 8443, Snapshot: snapshot()},
+			{Address: "node-a", ApiPort: 8443, Snapshot: snapshot()},
+		},
+	}
+
+	names := []string{}
+	for _, n := range []string{"node-a:8443", "node-b:8443"} {
+		for _, name := range []string{"content/c1", "content/c2", "content/c3", "flowfile", "heap", "provenance/p1", "provenance/p2"} {
+			names = append(names, n+"/"+name)
+		}
+	}
+
+	expected := strings.Join(names, ",")
+
+	for i := 0; i < 10; i++ {
+		names := []string{}
+		for _, v := range diagnostics.Check(DiagnosticsThresholds{Heap: 90, Repository: 90}) {
+			names = append(names, v.Node+"/"+v.Name)
+		}
+
+		if strings.Join(names, ",") != expected {
+			t.Fatalf("violations = %v, want %v", names, expected)
+		}
+	}
+}
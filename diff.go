@@ -0,0 +1,294 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type ChangeType string
+
+const (
+	Added    ChangeType = "ADDED"
+	Removed  ChangeType = "REMOVED"
+	Modified ChangeType = "MODIFIED"
+)
+
+func (t ChangeType) symbol() string {
+	switch t {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	case Modified:
+		return "~"
+	}
+
+	return "?"
+}
+
+type PropertyChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type FlowChange struct {
+	Change     ChangeType       `json:"change"`
+	Type       string           `json:"type"`
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Path       string           `json:"path"`
+	Properties []PropertyChange `json:"properties,omitempty"`
+}
+
+type FlowDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []*FlowChange `json:"changes"`
+}
+
+func (d *FlowDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *FlowDiff) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "version %v -> %v: %v change(s)\n", d.From, d.To, len(d.Changes))
+
+	for _, c := range d.Changes {
+		fmt.Fprintf(w, "%s %s %s/%s\n", c.Change.symbol(), c.Type, c.Path, c.Name)
+		for _, p := range c.Properties {
+			fmt.Fprintf(w, "    %s: %q -> %q\n", p.Name, p.From, p.To)
+		}
+	}
+}
+
+var snapshotTypes = map[string]string{
+	"processGroups":       ProcessGroupTitle,
+	"remoteProcessGroups": RemoteProcessGroupTitle,
+	"processors":          ProcessorTitle,
+	"connections":         ConnectionTitle,
+	"inputPorts":          InputPortTitle,
+	"outputPorts":         OutputPortTitle,
+	"funnels":             "Funnel",
+	"labels":              "Label",
+	"controllerServices":  "Controller Service",
+}
+
+var ignoredSnapshotFields = map[string]bool{
+	"position":             true,
+	"bends":                true,
+	"labelIndex":           true,
+	"zIndex":               true,
+	"componentType":        true,
+	"groupIdentifier":      true,
+	"versionedComponentId": true,
+}
+
+type snapshotItem struct {
+	Type   string
+	Name   string
+	Path   string
+	Group  string
+	Fields map[string]string
+}
+
+// DiffSnapshots compares the components of two snapshots by their identifier.
+// A component moved to another process group is modified with a "group"
+// property change from the old to the new path.
+func DiffSnapshots(from *FlowSnapshot, to *FlowSnapshot) *FlowDiff {
+	diff := &FlowDiff{
+		From:    int(from.Version.Version),
+		To:      int(to.Version.Version),
+		Changes: []*FlowChange{},
+	}
+
+	a := map[string]*snapshotItem{}
+	b := map[string]*snapshotItem{}
+
+	collectSnapshotItems("", from.Contents, a)
+	collectSnapshotItems("", to.Contents, b)
+
+	for id, old := range a {
+		item, ok := b[id]
+		if !ok {
+			diff.Changes = append(diff.Changes, &FlowChange{
+				Change: Removed,
+				Type:   old.Type,
+				ID:     id,
+				Name:   old.Name,
+				Path:   old.Path,
+			})
+			continue
+		}
+
+		properties := diffFields(old.Fields, item.Fields)
+		if old.Group != item.Group {
+			properties = append([]PropertyChange{{
+				Name: "group",
+				From: old.Path,
+				To:   item.Path,
+			}}, properties...)
+		}
+
+		if len(properties) > 0 {
+			diff.Changes = append(diff.Changes, &FlowChange{
+				Change:     Modified,
+				Type:       item.Type,
+				ID:         id,
+				Name:       item.Name,
+				Path:       item.Path,
+				Properties: properties,
+			})
+		}
+	}
+
+	for id, item := range b {
+		if _, ok := a[id]; !ok {
+			diff.Changes = append(diff.Changes, &FlowChange{
+				Change: Added,
+				Type:   item.Type,
+				ID:     id,
+				Name:   item.Name,
+				Path:   item.Path,
+			})
+		}
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		x, y := diff.Changes[i], diff.Changes[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+
+		if x.Type != y.Type {
+			return x.Type < y.Type
+		}
+
+		if x.Name != y.Name {
+			return x.Name < y.Name
+		}
+
+		if x.Change != y.Change {
+			return x.Change < y.Change
+		}
+
+		return x.ID < y.ID
+	})
+
+	return diff
+}
+
+func collectSnapshotItems(path string, group map[string]interface{}, items map[string]*snapshotItem) {
+	for key, title := range snapshotTypes {
+		list, ok := group[key].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, v := range list {
+			o, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			id, _ := o["identifier"].(string)
+			name, _ := o["name"].(string)
+			group, _ := o["groupIdentifier"].(string)
+
+			if len(name) == 0 && key == "connections" {
+				source, _ := o["source"].(map[string]interface{})
+				destination, _ := o["destination"].(map[string]interface{})
+				name = fmt.Sprintf("%v -> %v", source["name"], destination["name"])
+			}
+
+			fields := map[string]string{}
+			for k, v := range o {
+				if ignoredSnapshotFields[k] {
+					continue
+				}
+
+				if _, ok := snapshotTypes[k]; ok && key == "processGroups" {
+					continue
+				}
+
+				flattenField(k, v, fields)
+			}
+
+			items[id] = &snapshotItem{
+				Type:   title,
+				Name:   name,
+				Path:   path,
+				Group:  group,
+				Fields: fields,
+			}
+
+			if key == "processGroups" {
+				collectSnapshotItems(path+"/"+name, o, items)
+			}
+		}
+	}
+}
+
+func flattenField(name string, val interface{}, fields map[string]string) {
+	switch v := val.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, o := range v {
+			flattenField(name+"."+k, o, fields)
+		}
+	case []interface{}:
+		data, _ := json.Marshal(v)
+		fields[name] = string(data)
+	case string:
+		fields[name] = v
+	default:
+		fields[name] = fmt.Sprintf("%v", v)
+	}
+}
+
+func diffFields(a map[string]string, b map[string]string) []PropertyChange {
+	result := []PropertyChange{}
+
+	for k, v := range a {
+		if n, ok := b[k]; !ok || n != v {
+			result = append(result, PropertyChange{
+				Name: k,
+				From: v,
+				To:   n,
+			})
+		}
+	}
+
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			result = append(result, PropertyChange{
+				Name: k,
+				To:   v,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
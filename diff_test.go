@@ -0,0 +1,125 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testSnapshot(t *testing.T, version int, contents string) *FlowSnapshot {
+	snapshot := &FlowSnapshot{
+		Version: ProcessGroupVersion{Version: float64(version)},
+	}
+
+	err := json.Unmarshal([]byte(contents), &snapshot.Contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return snapshot
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	base := `{"identifier":"root","processors":[
+		{"identifier":"p1","name":"Log","groupIdentifier":"root","properties":{"level":"info"},"position":{"x":0,"y":0}}
+	],"processGroups":[
+		{"identifier":"g1","name":"Inner","groupIdentifier":"root","processors":[]}
+	]}`
+
+	tests := []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			contents: strings.Replace(base, `"x":0`, `"x":100`, 1),
+			expected: "version 1 -> 2: 0 change(s)\n",
+		},
+		{
+			name: "added",
+			contents: strings.Replace(base, `"processors":[]`, `"processors":[
+				{"identifier":"p3","name":"Put","groupIdentifier":"g1"},
+				{"identifier":"p2","name":"Put","groupIdentifier":"g1"}
+			]`, 1),
+			expected: "version 1 -> 2: 2 change(s)\n" +
+				"+ Processor /Inner/Put\n" +
+				"+ Processor /Inner/Put\n",
+		},
+		{
+			name:     "removed",
+			contents: `{"identifier":"root","processGroups":[{"identifier":"g1","name":"Inner","groupIdentifier":"root"}]}`,
+			expected: "version 1 -> 2: 1 change(s)\n" +
+				"- Processor /Log\n",
+		},
+		{
+			name:     "changed property",
+			contents: strings.Replace(base, `"level":"info"`, `"level":"debug","format":"json"`, 1),
+			expected: "version 1 -> 2: 1 change(s)\n" +
+				"~ Processor /Log\n" +
+				"    properties.format: \"\" -> \"json\"\n" +
+				"    properties.level: \"info\" -> \"debug\"\n",
+		},
+		{
+			name: "moved",
+			contents: `{"identifier":"root","processGroups":[
+				{"identifier":"g1","name":"Inner","groupIdentifier":"root","processors":[
+					{"identifier":"p1","name":"Log","groupIdentifier":"g1","properties":{"level":"info"},"position":{"x":0,"y":0}}
+				]}
+			]}`,
+			expected: "version 1 -> 2: 1 change(s)\n" +
+				"~ Processor /Inner/Log\n" +
+				"    group: \"\" -> \"/Inner\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSnapshots(testSnapshot(t, 1, base), testSnapshot(t, 2, tt.contents))
+
+			var output strings.Builder
+			diff.Fprint(&output)
+
+			if output.String() != tt.expected {
+				t.Errorf("diff:\n%v\nwant:\n%v", output.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshotsOrder(t *testing.T) {
+	from := testSnapshot(t, 1, `{"processors":[{"identifier":"p2","name":"Log","groupIdentifier":"root"}]}`)
+	to := testSnapshot(t, 2, `{"processors":[
+		{"identifier":"p3","name":"Log","groupIdentifier":"root"},
+		{"identifier":"p1","name":"Log","groupIdentifier":"root"}
+	]}`)
+
+	for i := 0; i < 10; i++ {
+		diff := DiffSnapshots(from, to)
+
+		ids := []string{}
+		for _, c := range diff.Changes {
+			ids = append(ids, string(c.Change)+" "+c.ID)
+		}
+
+		if strings.Join(ids, ",") != "ADDED p1,ADDED p3,REMOVED p2" {
+			t.Fatalf("changes = %v", ids)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	nifi "github.com/zauberhaus/nifi-api-client"
+)
+
+type metric struct {
+	attribute string
+	name      string
+	help      string
+}
+
+var metrics = map[nifi.NiFiType][]metric{
+	nifi.ProcessGroup: {
+		{"flowFilesQueued", "queued_flowfiles", "Number of flowfiles queued in the process group"},
+		{"bytesQueued", "queued_bytes", "Size of the flowfiles queued in the process group"},
+		{"bytesIn", "bytes_in", "Bytes received in the last 5 minutes"},
+		{"bytesOut", "bytes_out", "Bytes sent in the last 5 minutes"},
+		{"flowFilesIn", "flowfiles_in", "Flowfiles received in the last 5 minutes"},
+		{"flowFilesOut", "flowfiles_out", "Flowfiles sent in the last 5 minutes"},
+		{"activeThreadCount", "active_threads", "Number of active threads"},
+	},
+	nifi.Processor: {
+		{"bytesIn", "bytes_in", "Bytes received in the last 5 minutes"},
+		{"bytesOut", "bytes_out", "Bytes sent in the last 5 minutes"},
+		{"bytesRead", "bytes_read", "Bytes read in the last 5 minutes"},
+		{"bytesWritten", "bytes_written", "Bytes written in the last 5 minutes"},
+		{"flowFilesIn", "flowfiles_in", "Flowfiles received in the last 5 minutes"},
+		{"flowFilesOut", "flowfiles_out", "Flowfiles sent in the last 5 minutes"},
+		{"taskCount", "tasks", "Tasks in the last 5 minutes"},
+		{"activeThreadCount", "active_threads", "Number of active threads"},
+	},
+	nifi.Connection: {
+		{"flowFilesQueued", "queued_flowfiles", "Number of flowfiles queued in the connection"},
+		{"bytesQueued", "queued_bytes", "Size of the flowfiles queued in the connection"},
+		{"percentUseCount", "backpressure_count_percent", "Queued flowfiles in percent of the object threshold"},
+		{"percentUseBytes", "backpressure_bytes_percent", "Queued size in percent of the size threshold"},
+		{"bytesIn", "bytes_in", "Bytes received in the last 5 minutes"},
+		{"bytesOut", "bytes_out", "Bytes sent in the last 5 minutes"},
+		{"flowFilesIn", "flowfiles_in", "Flowfiles received in the last 5 minutes"},
+		{"flowFilesOut", "flowfiles_out", "Flowfiles sent in the last 5 minutes"},
+	},
+	nifi.InputPort: {
+		{"bytesIn", "bytes_in", "Bytes received in the last 5 minutes"},
+		{"bytesOut", "bytes_out", "Bytes sent in the last 5 minutes"},
+		{"flowFilesIn", "flowfiles_in", "Flowfiles received in the last 5 minutes"},
+		{"flowFilesOut", "flowfiles_out", "Flowfiles sent in the last 5 minutes"},
+		{"activeThreadCount", "active_threads", "Number of active threads"},
+	},
+	nifi.OutputPort: {
+		{"bytesIn", "bytes_in", "Bytes received in the last 5 minutes"},
+		{"bytesOut", "bytes_out", "Bytes sent in the last 5 minutes"},
+		{"flowFilesIn", "flowfiles_in", "Flowfiles received in the last 5 minutes"},
+		{"flowFilesOut", "flowfiles_out", "Flowfiles sent in the last 5 minutes"},
+		{"activeThreadCount", "active_threads", "Number of active threads"},
+	},
+	nifi.RemoteProcessGroup: {
+		{"sentCount", "sent_flowfiles", "Flowfiles sent in the last 5 minutes"},
+		{"receivedCount", "received_flowfiles", "Flowfiles received in the last 5 minutes"},
+		{"activeThreadCount", "active_threads", "Number of active threads"},
+	},
+}
+
+var prefixes = map[nifi.NiFiType]string{
+	nifi.ProcessGroup:       "nifi_process_group_",
+	nifi.Processor:          "nifi_processor_",
+	nifi.Connection:         "nifi_connection_",
+	nifi.InputPort:          "nifi_input_port_",
+	nifi.OutputPort:         "nifi_output_port_",
+	nifi.RemoteProcessGroup: "nifi_remote_process_group_",
+}
+
+type Exporter struct {
+	client      *nifi.Client
+	ids         []string
+	ttl         time.Duration
+	cluster     bool
+	diagnostics bool
+
+	mutex   sync.Mutex
+	cache   []byte
+	updated time.Time
+	errors  map[string]int
+}
+
+type Option func(*Exporter)
+
+func WithoutCluster() Option {
+	return func(e *Exporter) {
+		e.cluster = false
+	}
+}
+
+func WithoutDiagnostics() Option {
+	return func(e *Exporter) {
+		e.diagnostics = false
+	}
+}
+
+// New creates an exporter for the process groups ids. The metrics are
+// collected at most once per ttl, concurrent scrapes wait for the running
+// collection and share its result. Cluster metrics are disabled, if the
+// server isn't clustered.
+func New(client *nifi.Client, ids []string, ttl time.Duration, options ...Option) *Exporter {
+	e := &Exporter{
+		client:      client,
+		ids:         ids,
+		ttl:         ttl,
+		cluster:     true,
+		diagnostics: true,
+		errors:      map[string]int{},
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return e
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := e.Collect()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(data)
+}
+
+// Collect returns the metrics in the text format. If the components can't be
+// collected, nifi_up is 0 and the metrics are returned with the error without
+// caching them.
+func (e *Exporter) Collect() ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.cache != nil && time.Since(e.updated) < e.ttl {
+		return e.cache, nil
+	}
+
+	start := time.Now()
+	set := newMetricSet()
+
+	err := e.collectComponents(set)
+	e.countError(set, "components", err)
+
+	if err == nil {
+		e.collectOptional(set)
+	}
+
+	up := 1.0
+	if err != nil {
+		up = 0
+	}
+
+	set.add("nifi_up", "gauge", "NiFi could be reached and the components were collected", nil, up)
+	set.add("nifi_scrape_duration_seconds", "gauge", "Duration of the collection from NiFi", nil, time.Since(start).Seconds())
+
+	var buffer bytes.Buffer
+	set.write(&buffer)
+
+	if err != nil {
+		return buffer.Bytes(), err
+	}
+
+	e.cache = buffer.Bytes()
+	e.updated = time.Now()
+
+	return e.cache, nil
+}
+
+// collectOptional collects the cluster and diagnostics metrics, which don't
+// fail the scrape. Cluster metrics are only disabled, if the 409 is confirmed
+// by the cluster summary, because disconnected nodes answer with 409 too.
+func (e *Exporter) collectOptional(set *metricSet) {
+	if e.cluster {
+		err := e.collectCluster(set)
+		if nifi.IsStatus(err, http.StatusConflict) {
+			clustered, cerr := e.client.Clustered()
+			if cerr == nil && !clustered {
+				e.cluster = false
+				err = nil
+			}
+		}
+
+		if e.cluster {
+			e.countError(set, "cluster", err)
+		}
+	}
+
+	if e.diagnostics {
+		err := e.collectDiagnostics(set)
+		e.countError(set, "diagnostics", err)
+	}
+}
+
+// countError counts the failed collections.
+func (e *Exporter) countError(set *metricSet, collector string, err error) {
+	if err != nil {
+		e.errors[collector]++
+	}
+
+	set.add("nifi_exporter_collect_errors_total", "counter", "Number of failed collections", []string{"collector", collector}, float64(e.errors[collector]))
+}
+
+func (e *Exporter) collectComponents(set *metricSet) error {
+	components, err := e.client.All(e.ids, nifi.AllTypes, true)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range components {
+		prefix, ok := prefixes[c.Type]
+		if !ok {
+			continue
+		}
+
+		labels := []string{"id", c.ID, "name", c.Name, "path", c.Path}
+
+		for _, m := range metrics[c.Type] {
+			if val, ok := c.Attributes[m.attribute].(float64); ok {
+				set.add(prefix+m.name, "gauge", m.help, labels, val)
+			}
+		}
+
+		if status, ok := c.Attributes["runStatus"].(string); ok {
+			set.add(prefix+"run_status", "gauge", "Run status of the component", append(labels, "status", status), 1)
+		}
+
+		if status, ok := c.Attributes["transmissionStatus"].(string); ok {
+			set.add(prefix+"transmission_status", "gauge", "Transmission status of the remote process group", append(labels, "status", status), 1)
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) collectCluster(set *metricSet) error {
+	nodes, err := e.client.ClusterNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		labels := []string{"node", fmt.Sprintf("%v:%v", n.Address, n.ApiPort), "id", n.ID}
+
+		connected := 0.0
+		if n.Status == nifi.CONNECTED {
+			connected = 1
+		}
+
+		set.add("nifi_cluster_node_connected", "gauge", "Node is connected to the cluster", labels, connected)
+		set.add("nifi_cluster_node_status", "gauge", "Status of the cluster node", append(labels, "status", n.Status), 1)
+		set.add("nifi_cluster_node_queued_flowfiles", "gauge", "Number of flowfiles queued on the node", labels, float64(n.FlowFilesQueued))
+		set.add("nifi_cluster_node_active_threads", "gauge", "Number of active threads on the node", labels, float64(n.ActiveThreadCount))
+	}
+
+	return nil
+}
+
+func (e *Exporter) collectDiagnostics(set *metricSet) error {
+	diagnostics, err := e.client.SystemDiagnostics(true)
+	if err != nil {
+		return err
+	}
+
+	if len(diagnostics.Nodes) == 0 {
+		if diagnostics.Aggregate != nil {
+			addDiagnostics(set, nil, diagnostics.Aggregate)
+		}
+
+		return nil
+	}
+
+	for _, n := range diagnostics.Nodes {
+		if n.Snapshot != nil {
+			addDiagnostics(set, []string{"node", fmt.Sprintf("%v:%v", n.Address, n.ApiPort)}, n.Snapshot)
+		}
+	}
+
+	return nil
+}
+
+func addDiagnostics(set *metricSet, labels []string, s *nifi.DiagnosticsSnapshot) {
+	set.add("nifi_jvm_heap_used_bytes", "gauge", "Used heap", labels, float64(s.UsedHeapBytes))
+	set.add("nifi_jvm_heap_max_bytes", "gauge", "Maximum heap", labels, float64(s.MaxHeapBytes))
+	set.add("nifi_jvm_non_heap_used_bytes", "gauge", "Used non heap", labels, float64(s.UsedNonHeapBytes))
+	set.add("nifi_jvm_threads", "gauge", "Number of threads", labels, float64(s.TotalThreads))
+	set.add("nifi_jvm_daemon_threads", "gauge", "Number of daemon threads", labels, float64(s.DaemonThreads))
+	set.add("nifi_system_load_average", "gauge", "System load average", labels, s.ProcessorLoadAverage)
+	set.add("nifi_uptime_seconds", "gauge", "Uptime of the node", labels, s.UptimeDuration().Seconds())
+
+	for name, r := range s.Repositories() {
+		l := append(append([]string{}, labels...), "repository", name)
+		set.add("nifi_repository_used_bytes", "gauge", "Used space of the repository", l, float64(r.UsedBytes))
+		set.add("nifi_repository_total_bytes", "gauge", "Total space of the repository", l, float64(r.TotalBytes))
+	}
+
+	for _, gc := range s.GarbageCollection {
+		l := append(append([]string{}, labels...), "collector", gc.Name)
+		set.add("nifi_jvm_gc_collections_total", "counter", "Number of garbage collections", l, float64(gc.CollectionCount))
+		set.add("nifi_jvm_gc_collection_seconds_total", "counter", "Time spent in garbage collections", l, float64(gc.CollectionMillis)/1000)
+	}
+}
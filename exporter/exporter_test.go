@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	nifi "github.com/zauberhaus/nifi-api-client"
+)
+
+type testServer struct {
+	status    int
+	clustered bool
+	summaries int
+}
+
+func (s *testServer) client(t *testing.T) *nifi.Client {
+	mux := http.NewServeMux()
+
+	json := func(w http.ResponseWriter, status int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+
+	mux.HandleFunc("/nifi-api/process-groups/root", func(w http.ResponseWriter, r *http.Request) {
+		json(w, http.StatusOK, `{"id":"root-id","component":{"id":"root-id","name":"NiFi Flow"}}`)
+	})
+
+	mux.HandleFunc("/nifi-api/flow/process-groups/root-id/status", func(w http.ResponseWriter, r *http.Request) {
+		json(w, s.status, `{"processGroupStatus":{"id":"root-id","name":"NiFi Flow","aggregateSnapshot":{"id":"root-id","name":"NiFi Flow","flowFilesQueued":3}}}`)
+	})
+
+	mux.HandleFunc("/nifi-api/controller/cluster", func(w http.ResponseWriter, r *http.Request) {
+		json(w, http.StatusConflict, `{}`)
+	})
+
+	mux.HandleFunc("/nifi-api/flow/cluster/summary", func(w http.ResponseWriter, r *http.Request) {
+		s.summaries++
+		if s.clustered {
+			json(w, http.StatusOK, `{"clusterSummary":{"clustered":true,"connectedToCluster":false}}`)
+		} else {
+			json(w, http.StatusOK, `{"clusterSummary":{"clustered":false}}`)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := nifi.ConnectWithOptions(u, nifi.WithoutTokenCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusOK)
+	}
+
+	data, err := ioutil.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestExporterComponentsFailure(t *testing.T) {
+	server := &testServer{status: http.StatusInternalServerError}
+	e := New(server.client(t), []string{"root-id"}, time.Minute, WithoutDiagnostics())
+
+	for _, count := range []string{"1", "2"} {
+		data := scrape(t, e)
+
+		for _, line := range []string{"nifi_up 0", `nifi_exporter_collect_errors_total{collector="components"} ` + count} {
+			if !strings.Contains(data, line+"\n") {
+				t.Errorf("%v is missing in\n%v", line, data)
+			}
+		}
+	}
+}
+
+func TestExporterStandalone(t *testing.T) {
+	server := &testServer{status: http.StatusOK}
+	e := New(server.client(t), []string{"root-id"}, 0, WithoutDiagnostics())
+
+	data := scrape(t, e)
+	if !strings.Contains(data, "nifi_up 1\n") || strings.Contains(data, `collector="cluster"`) {
+		t.Errorf("unexpected metrics:\n%v", data)
+	}
+
+	scrape(t, e)
+
+	if server.summaries != 1 {
+		t.Errorf("cluster summary requested %v times, want 1", server.summaries)
+	}
+}
+
+func TestExporterDisconnectedNode(t *testing.T) {
+	server := &testServer{status: http.StatusOK, clustered: true}
+	e := New(server.client(t), []string{"root-id"}, 0, WithoutDiagnostics())
+
+	scrape(t, e)
+	data := scrape(t, e)
+
+	if !strings.Contains(data, `nifi_exporter_collect_errors_total{collector="cluster"} 2`+"\n") {
+		t.Errorf("cluster errors aren't counted:\n%v", data)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type family struct {
+	kind    string
+	help    string
+	samples []string
+}
+
+type metricSet struct {
+	families map[string]*family
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{
+		families: map[string]*family{},
+	}
+}
+
+// add appends a sample, labels is a flat list of label names and values.
+func (s *metricSet) add(name string, kind string, help string, labels []string, value float64) {
+	f, ok := s.families[name]
+	if !ok {
+		f = &family{
+			kind: kind,
+			help: help,
+		}
+		s.families[name] = f
+	}
+
+	var sb strings.Builder
+	sb.WriteString(name)
+
+	if len(labels) > 1 {
+		sb.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", labels[i], escape(labels[i+1]))
+		}
+		sb.WriteString("}")
+	}
+
+	sb.WriteString(" ")
+	sb.WriteString(formatValue(value))
+
+	f.samples = append(f.samples, sb.String())
+}
+
+func (s *metricSet) write(w io.Writer) {
+	names := make([]string, 0, len(s.families))
+	for k := range s.families {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := s.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", name, f.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+		for _, sample := range f.samples {
+			fmt.Fprintln(w, sample)
+		}
+	}
+}
+
+func escape(txt string) string {
+	txt = strings.Replace(txt, `\`, `\\`, -1)
+	txt = strings.Replace(txt, "\n", `\n`, -1)
+	return strings.Replace(txt, `"`, `\"`, -1)
+}
+
+func formatValue(val float64) string {
+	switch {
+	case math.IsInf(val, 1):
+		return "+Inf"
+	case math.IsInf(val, -1):
+		return "-Inf"
+	case math.IsNaN(val):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
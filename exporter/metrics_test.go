@@ -0,0 +1,54 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMetricSetWrite(t *testing.T) {
+	set := newMetricSet()
+	set.add("nifi_up", "gauge", "NiFi is up", nil, 1)
+	set.add("nifi_connection_queued_bytes", "gauge", "Queued bytes", []string{"id", "1", "name", "a \"b\"\\c\nd"}, 1.5e10)
+	set.add("nifi_connection_queued_bytes", "gauge", "Queued bytes", []string{"id", "2", "name", "e"}, 0)
+	set.add("nifi_ratio", "gauge", "Special values", []string{"v", "inf"}, math.Inf(1))
+	set.add("nifi_ratio", "gauge", "Special values", []string{"v", "-inf"}, math.Inf(-1))
+	set.add("nifi_ratio", "gauge", "Special values", []string{"v", "nan"}, math.NaN())
+
+	var buffer bytes.Buffer
+	set.write(&buffer)
+
+	expected := `# HELP nifi_connection_queued_bytes Queued bytes
+# TYPE nifi_connection_queued_bytes gauge
+nifi_connection_queued_bytes{id="1",name="a \"b\"\\c\nd"} 1.5e+10
+nifi_connection_queued_bytes{id="2",name="e"} 0
+# HELP nifi_ratio Special values
+# TYPE nifi_ratio gauge
+nifi_ratio{v="inf"} +Inf
+nifi_ratio{v="-inf"} -Inf
+nifi_ratio{v="nan"} NaN
+# HELP nifi_up NiFi is up
+# TYPE nifi_up gauge
+nifi_up 1
+`
+
+	if buffer.String() != expected {
+		t.Errorf("output:\n%v\nwant:\n%v", buffer.String(), expected)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) ExportFlowDefinition(groupID string, w io.Writer) error {
+	u := c.URL(fmt.Sprintf("/process-groups/%v/download", groupID))
+	return c.CallStream(Get, u, nil, "", w)
+}
+
+func (c *Client) UploadFlowDefinition(parentID string, r io.Reader, name string, position Position) (*Component, error) {
+	fields := map[string]string{
+		"groupName":                    name,
+		"positionX":                    strconv.FormatFloat(position.X, 'f', -1, 64),
+		"positionY":                    strconv.FormatFloat(position.Y, 'f', -1, 64),
+		"clientId":                     uuid.New().String(),
+		"disconnectedNodeAcknowledged": "false",
+	}
+
+	u := c.URL(fmt.Sprintf("/process-groups/%v/process-groups/upload", parentID))
+
+	response, err := c.CallMultipart(Post, u, fields, "file", name+".json", r)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProcessGroupComponent(response)
+}
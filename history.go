@@ -0,0 +1,216 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+type FieldDescriptor struct {
+	Field       string `json:"field"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Formatter   string `json:"formatter"`
+}
+
+type StatusSample struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Metrics   map[string]int64 `json:"statusMetrics"`
+}
+
+func (s *StatusSample) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Timestamp interface{}      `json:"timestamp"`
+		Metrics   map[string]int64 `json:"statusMetrics"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	switch t := raw.Timestamp.(type) {
+	case float64:
+		s.Timestamp = time.Unix(0, int64(t)*int64(time.Millisecond))
+	case string:
+		s.Timestamp = parseNiFiTime(t)
+	}
+
+	s.Metrics = raw.Metrics
+
+	return nil
+}
+
+type StatusSeries []StatusSample
+
+func (s StatusSeries) Values(field string) []float64 {
+	result := []float64{}
+	for _, sample := range s {
+		if val, ok := sample.Metrics[field]; ok {
+			result = append(result, float64(val))
+		}
+	}
+
+	return result
+}
+
+// Interval returns the average distance between two samples.
+func (s StatusSeries) Interval() time.Duration {
+	if len(s) < 2 {
+		return 0
+	}
+
+	return s[len(s)-1].Timestamp.Sub(s[0].Timestamp) / time.Duration(len(s)-1)
+}
+
+// Rate returns the average per second of a field, which NiFi reports per
+// sample interval, e.g. bytesRead.
+func (s StatusSeries) Rate(field string) float64 {
+	interval := s.Interval()
+	values := s.Values(field)
+
+	if interval <= 0 || len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / (float64(len(values)) * interval.Seconds())
+}
+
+func (s StatusSeries) Mean(field string) float64 {
+	values := s.Values(field)
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+// Percentile returns the p-th percentile (0-100) of a field with linear
+// interpolation between the closest ranks.
+func (s StatusSeries) Percentile(field string, p float64) float64 {
+	values := s.Values(field)
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Float64s(values)
+
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower < 0 {
+		return values[0]
+	}
+
+	if upper >= len(values) {
+		return values[len(values)-1]
+	}
+
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
+
+type NodeStatusHistory struct {
+	NodeID  string       `json:"nodeId"`
+	Address string       `json:"address"`
+	ApiPort int          `json:"apiPort"`
+	Samples StatusSeries `json:"statusSnapshots"`
+}
+
+type StatusHistory struct {
+	Details   map[string]string   `json:"componentDetails"`
+	Fields    []FieldDescriptor   `json:"fieldDescriptors"`
+	Aggregate StatusSeries        `json:"aggregateSnapshots"`
+	Nodes     []NodeStatusHistory `json:"nodeSnapshots"`
+}
+
+func (h *StatusHistory) Node(id string) StatusSeries {
+	samples, _ := h.node(id)
+	return samples
+}
+
+func (h *StatusHistory) node(id string) (StatusSeries, bool) {
+	for _, n := range h.Nodes {
+		if n.NodeID == id {
+			return n.Samples, true
+		}
+	}
+
+	return nil, false
+}
+
+func (h *StatusHistory) Field(name string) *FieldDescriptor {
+	for i, f := range h.Fields {
+		if f.Field == name {
+			return &h.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) StatusHistory(t NiFiType, id string) (*StatusHistory, error) {
+	switch t {
+	case ProcessGroup, RemoteProcessGroup, Processor, Connection:
+	default:
+		return nil, fmt.Errorf("status history isn't supported for %v", t)
+	}
+
+	response, err := c.Get(fmt.Sprintf("/flow/%v/%v/status/history", t.Resource(), id))
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		StatusHistory *StatusHistory `json:"statusHistory"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.StatusHistory == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	history := output.StatusHistory
+	if len(c.node) > 0 {
+		samples, ok := history.node(c.node)
+		if !ok {
+			return nil, fmt.Errorf("status history of node %v: %w", c.node, ErrNotFound)
+		}
+
+		history.Aggregate = samples
+	}
+
+	return history, nil
+}
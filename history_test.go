@@ -0,0 +1,84 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func newNodeTestClient(t *testing.T) *Client {
+	mux := http.NewServeMux()
+
+	json := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+
+	mux.HandleFunc("/nifi-api/flow/processors/p1/status/history", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"statusHistory":{
+			"aggregateSnapshots":[{"timestamp":1600000000000,"statusMetrics":{"flowFilesIn":3}}],
+			"nodeSnapshots":[{"nodeId":"n1","statusSnapshots":[{"timestamp":1600000000000,"statusMetrics":{"flowFilesIn":1}}]}]
+		}}`)
+	})
+
+	mux.HandleFunc("/nifi-api/flow/process-groups/root-id/status", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"processGroupStatus":{
+			"aggregateSnapshot":{"id":"root-id","name":"NiFi Flow"},
+			"nodeSnapshots":[{"nodeId":"n1","statusSnapshot":{"id":"root-id","name":"NiFi Flow"}}]
+		}}`)
+	})
+
+	return newTestClient(t, mux)
+}
+
+func TestStatusHistoryNode(t *testing.T) {
+	client := newNodeTestClient(t)
+
+	history, err := client.Node("n1").StatusHistory(Processor, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values := history.Aggregate.Values("flowFilesIn"); len(values) != 1 || values[0] != 1 {
+		t.Errorf("flowFilesIn = %v, want [1]", values)
+	}
+
+	_, err = client.Node("unknown").StatusHistory(Processor, "p1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestStatusSnapshotNode(t *testing.T) {
+	client := newNodeTestClient(t)
+
+	list, err := client.Node("n1").All([]string{"root-id"}, ProcessGroup, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || list[0].ID != "root-id" {
+		t.Errorf("components = %v", list)
+	}
+
+	_, err = client.Node("unknown").All([]string{"root-id"}, ProcessGroup, false)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrNotFound)
+	}
+}
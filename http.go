@@ -17,98 +17,290 @@ limitations under the License.
 package nifi
 
 import (
-	"bytes"
+	"context"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/ed25519"
-	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"time"
 
 	"golang.org/x/crypto/pkcs12"
 )
 
+var (
+	ErrConflictingOptions = fmt.Errorf("conflicting options")
+)
+
 type HttpClient struct {
 	client *http.Client
 }
 
 func NewHttpCertClient(file string, password string, ca string, insecureSkipVerify bool) (*HttpClient, error) {
+	return NewHttpClientWithOptions(NewOptions(
+		WithPKCS12(file, password),
+		WithCA(ca),
+		WithInsecureSkipVerify(insecureSkipVerify),
+	))
+}
+
+func NewHttpClient(ca string, insecureSkipVerify bool) (*HttpClient, error) {
+	return NewHttpClientWithOptions(NewOptions(
+		WithCA(ca),
+		WithInsecureSkipVerify(insecureSkipVerify),
+	))
+}
+
+func NewHttpClientWithOptions(o *Options) (*HttpClient, error) {
 	rc := &HttpClient{}
 
-	client, err := rc.createClient(file, password, ca, insecureSkipVerify)
+	config, err := rc.tlsConfig(o)
 	if err != nil {
 		return nil, err
 	}
 
-	rc.client = client
+	transport := o.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if t, ok := transport.(*http.Transport); ok {
+		transport = configureTransport(t.Clone(), config, o)
+	}
+
+	rc.client = &http.Client{
+		Transport: &roundTripper{
+			next:    transport,
+			options: o,
+		},
+		Timeout: o.Timeout,
+	}
+
 	return rc, nil
 }
 
-func NewHttpClient(ca string, insecureSkipVerify bool) (*HttpClient, error) {
-	rc := &HttpClient{}
-	var pool *x509.CertPool
+func configureTransport(t *http.Transport, config *tls.Config, o *Options) http.RoundTripper {
+	t.TLSClientConfig = config
 
-	if ca != "" {
-		pool = x509.NewCertPool()
+	if o.Proxy != nil {
+		t.Proxy = o.Proxy
+	}
 
-		certs, err := rc.LoadPemCertificate(ca)
-		if err != nil {
-			return nil, err
-		}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
 
-		for _, cert := range certs {
-			pool.AddCert(cert)
+	if o.DialTimeout > 0 || o.KeepAlive > 0 {
+		dialer = &net.Dialer{
+			Timeout:   o.DialTimeout,
+			KeepAlive: o.KeepAlive,
 		}
+
+		t.DialContext = dialer.DialContext
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs:            pool,
-			InsecureSkipVerify: insecureSkipVerify,
-		},
+	if o.HandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = o.HandshakeTimeout
 	}
-	rc.client = &http.Client{
-		Transport: transport,
+
+	if o.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = o.IdleConnTimeout
 	}
 
-	return rc, nil
+	if o.MaxIdleConns > 0 {
+		t.MaxIdleConns = o.MaxIdleConns
+		t.MaxIdleConnsPerHost = o.MaxIdleConns
+	}
+
+	if o.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = o.MaxConnsPerHost
+	}
+
+	if o.DisableHTTP2 {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if o.ProxyTLSConfig != nil && t.Proxy != nil {
+		return newProxyTLSTransport(t, dialer, o.ProxyTLSConfig)
+	}
+
+	return t
+}
+
+type proxyKey struct{}
+
+// proxyTLSTransport dials the TLS connections itself, because the transport
+// uses the TLS config of the server for HTTPS proxies too. The HTTPS proxy of
+// a request is passed to the dialer in the request context.
+type proxyTLSTransport struct {
+	*http.Transport
+
+	dialer *net.Dialer
+	config *tls.Config
+}
+
+func newProxyTLSTransport(t *http.Transport, dialer *net.Dialer, config *tls.Config) *proxyTLSTransport {
+	rc := &proxyTLSTransport{
+		Transport: t,
+		dialer:    dialer,
+		config:    config,
+	}
+
+	t.DialTLSContext = rc.dialTLS
+
+	return rc
 }
 
-func (c *HttpClient) createClient(file string, password string, ca string, insecureSkipVerify bool) (*http.Client, error) {
-	cert, pool, err := c.ReadPKCS12(file, password)
+func (t *proxyTLSTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	proxy, err := t.Proxy(r)
+	if err == nil && proxy != nil && proxy.Scheme == "https" {
+		r = r.WithContext(context.WithValue(r.Context(), proxyKey{}, proxy))
+	}
+
+	return t.Transport.RoundTrip(r)
+}
+
+// dialTLS is called for the first TLS hop, which is the proxy for proxied
+// requests and the server otherwise. The handshake has its own timeout like
+// the one of the transport.
+func (t *proxyTLSTransport) dialTLS(ctx context.Context, network string, addr string) (net.Conn, error) {
+	config := t.TLSClientConfig
+	if proxy, ok := ctx.Value(proxyKey{}).(*url.URL); ok && addr == proxyAddr(proxy) {
+		config = t.config
+	}
+
+	conn, err := t.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	config = config.Clone()
+	if len(config.ServerName) == 0 {
+		config.ServerName = host
+	}
+
+	if t.TLSHandshakeTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(t.TLSHandshakeTimeout))
+	}
+
+	tlsConn := tls.Client(conn, config)
+
+	err = tlsConn.Handshake()
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	if ca != "" {
-		certs, err := c.LoadPemCertificate(ca)
+	conn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
+
+func proxyAddr(proxy *url.URL) string {
+	port := proxy.Port()
+	if len(port) == 0 {
+		port = "443"
+	}
+
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+func (c *HttpClient) tlsConfig(o *Options) (*tls.Config, error) {
+	config := &tls.Config{}
+	if o.TLSConfig != nil {
+		config = o.TLSConfig.Clone()
+	}
+
+	pool := o.CAPool
+	if pool != nil {
+		pool = pool.Clone()
+	}
+
+	if len(o.PKCS12File) > 0 {
+		cert, cas, err := c.readPKCS12(o.PKCS12File, o.PKCS12Password)
 		if err != nil {
 			return nil, err
 		}
 
+		config.Certificates = append(config.Certificates, *cert)
+
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		for _, ca := range cas {
+			pool.AddCert(ca)
+		}
+	}
+
+	if o.CA != "" {
+		certs, err := c.LoadPemCertificate(o.CA)
+		if err != nil {
+			return nil, err
+		}
+
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+
 		for _, cert := range certs {
 			pool.AddCert(cert)
 		}
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs:            pool,
-			Certificates:       []tls.Certificate{*cert},
-			InsecureSkipVerify: insecureSkipVerify,
-		},
+	if pool != nil {
+		config.RootCAs = pool
 	}
-	return &http.Client{
-		Transport: transport,
-	}, nil
+
+	config.Certificates = append(config.Certificates, o.Certificates...)
+
+	if len(o.PEMCertFile) > 0 {
+		if len(config.Certificates) > 0 {
+			return nil, fmt.Errorf("PEM key pair and another client certificate: %w", ErrConflictingOptions)
+		}
+
+		reloader, err := NewCertificateReloader(o.PEMCertFile, o.PEMKeyFile, o.PEMKeyPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	if o.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
 }
 
 func (c *HttpClient) ReadPKCS12(file string, password string) (*tls.Certificate, *x509.CertPool, error) {
+	cert, cas, err := c.readPKCS12(file, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+
+	return cert, pool, nil
+}
+
+func (c *HttpClient) readPKCS12(file string, password string) (*tls.Certificate, []*x509.Certificate, error) {
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, nil, err
@@ -119,7 +311,7 @@ func (c *HttpClient) ReadPKCS12(file string, password string) (*tls.Certificate,
 		return nil, nil, err
 	}
 
-	pool := x509.NewCertPool()
+	cas := []*x509.Certificate{}
 	var tlsCert *tls.Certificate
 	var privateKey crypto.PrivateKey
 
@@ -140,7 +332,7 @@ func (c *HttpClient) ReadPKCS12(file string, password string) (*tls.Certificate,
 			}
 
 			if cert.IsCA {
-				pool.AddCert(cert)
+				cas = append(cas, cert)
 			} else {
 				tlsCert = &tls.Certificate{
 					Certificate: [][]byte{
@@ -152,8 +344,6 @@ func (c *HttpClient) ReadPKCS12(file string, password string) (*tls.Certificate,
 		}
 	}
 
-	tlsCert.PrivateKey = privateKey
-
 	if privateKey == nil {
 		return nil, nil, fmt.Errorf("private key is missing")
 	}
@@ -162,37 +352,14 @@ func (c *HttpClient) ReadPKCS12(file string, password string) (*tls.Certificate,
 		return nil, nil, fmt.Errorf("client certificate is missing")
 	}
 
-	switch pub := tlsCert.Leaf.PublicKey.(type) {
-	case *rsa.PublicKey:
-		priv, ok := privateKey.(*rsa.PrivateKey)
-		if !ok {
-			return nil, nil, errors.New("tls: private key type does not match public key type")
-		}
-		if pub.N.Cmp(priv.N) != 0 {
-			return nil, nil, errors.New("tls: private key does not match public key")
-		}
-	case *ecdsa.PublicKey:
-		priv, ok := privateKey.(*ecdsa.PrivateKey)
-		if !ok {
-			return nil, nil, errors.New("tls: private key type does not match public key type")
-		}
-		if pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
-			return nil, nil, errors.New("tls: private key does not match public key")
-		}
-	case ed25519.PublicKey:
-		priv, ok := privateKey.(ed25519.PrivateKey)
-		if !ok {
-			return nil, nil, errors.New("tls: private key type does not match public key type")
-		}
-		if !bytes.Equal(priv.Public().(ed25519.PublicKey), pub) {
-			return nil, nil, errors.New("tls: private key does not match public key")
-		}
-	default:
-		return nil, nil, errors.New("tls: unknown public key algorithm")
+	tlsCert.PrivateKey = privateKey
 
+	err = checkKeyPair(tlsCert.Leaf, privateKey)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return tlsCert, pool, nil
+	return tlsCert, cas, nil
 }
 
 func (c *HttpClient) LoadPemCertificate(path string) ([]*x509.Certificate, error) {
@@ -0,0 +1,204 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "proxy"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+// newTestProxy returns an HTTPS proxy with its own CA, which tunnels CONNECT
+// requests and records their targets.
+func newTestProxy(t *testing.T, targets chan<- string) (*httptest.Server, *x509.CertPool) {
+	cert, pool := newTestCertificate(t)
+
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		targets <- r.Host
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		conn, buffer, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+
+		go func() {
+			io.Copy(upstream, buffer)
+			upstream.Close()
+		}()
+
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	proxy.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	proxy.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	proxy.StartTLS()
+	t.Cleanup(proxy.Close)
+
+	return proxy, pool
+}
+
+func TestHTTPSProxyWithOwnCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	defer server.Close()
+
+	targets := make(chan string, 10)
+	proxy, proxyCAs := newTestProxy(t, targets)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(server.Certificate())
+
+	get := func(options ...Option) error {
+		client, err := NewHttpClientWithOptions(NewOptions(append(options, WithCAPool(serverCAs), WithProxyURL(proxyURL))...))
+		if err != nil {
+			return err
+		}
+
+		request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			return err
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err == nil && string(body) != "ok" {
+			t.Errorf("body = %q, want %q", body, "ok")
+		}
+
+		return err
+	}
+
+	if get() == nil {
+		t.Fatal("proxy with an unknown CA trusted")
+	}
+
+	err = get(WithProxyTLSConfig(&tls.Config{RootCAs: proxyCAs}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case target := <-targets:
+		if target != server.Listener.Addr().String() {
+			t.Errorf("tunnel to %v, want %v", target, server.Listener.Addr())
+		}
+	default:
+		t.Error("request didn't use the proxy")
+	}
+}
+
+func TestTransportHTTP2Unchanged(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		client, err := NewHttpClientWithOptions(NewOptions(WithTransport(&http.Transport{ForceAttemptHTTP2: force})))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		transport := client.client.Transport.(*roundTripper).next.(*http.Transport)
+		if transport.ForceAttemptHTTP2 != force {
+			t.Errorf("ForceAttemptHTTP2 = %v, want %v", transport.ForceAttemptHTTP2, force)
+		}
+	}
+}
+
+func TestConflictingClientCertificates(t *testing.T) {
+	cert, _ := newTestCertificate(t)
+
+	_, err := NewHttpClientWithOptions(NewOptions(
+		WithClientCertificate(cert),
+		WithPEMKeyPair("testdata/rsa.crt", "testdata/rsa.key", ""),
+	))
+	if !errors.Is(err, ErrConflictingOptions) {
+		t.Errorf("error = %v, want %v", err, ErrConflictingOptions)
+	}
+}
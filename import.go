@@ -0,0 +1,110 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type parameterContextReference struct {
+	ID string `json:"id"`
+}
+
+type importVersionControlInfo struct {
+	Registry string `json:"registryId"`
+	Bucket   string `json:"bucketId"`
+	Flow     string `json:"flowId"`
+	Version  int    `json:"version"`
+}
+
+type importComponent struct {
+	Position                  Position                   `json:"position"`
+	VersionControlInformation *importVersionControlInfo  `json:"versionControlInformation"`
+	ParameterContext          *parameterContextReference `json:"parameterContext,omitempty"`
+}
+
+type importEntity struct {
+	Revision                     *Revision        `json:"revision"`
+	Component                    *importComponent `json:"component"`
+	DisconnectedNodeAcknowledged bool             `json:"disconnectedNodeAcknowledged"`
+}
+
+func (c *Client) ImportVersionedFlow(parentGroupID string, registryID string, bucketID string, flowID string, version int, position Position) (*Component, error) {
+	return c.ImportVersionedFlowWithContext(parentGroupID, registryID, bucketID, flowID, version, position, "")
+}
+
+// ImportVersionedFlowWithContext creates a new process group from a registry
+// flow and binds it to the parameter context, if parameterContextID isn't
+// empty.
+func (c *Client) ImportVersionedFlowWithContext(parentGroupID string, registryID string, bucketID string, flowID string, version int, position Position, parameterContextID string) (*Component, error) {
+	registryID, bucketID, flowID, err := c.ResolveFlow(registryID, bucketID, flowID)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := &importEntity{
+		Revision: newRevisionZero(),
+		Component: &importComponent{
+			Position: position,
+			VersionControlInformation: &importVersionControlInfo{
+				Registry: registryID,
+				Bucket:   bucketID,
+				Flow:     flowID,
+				Version:  version,
+			},
+		},
+	}
+
+	if len(parameterContextID) > 0 {
+		entity.Component.ParameterContext = &parameterContextReference{
+			ID: parameterContextID,
+		}
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.Post(fmt.Sprintf("/process-groups/%v/process-groups", parentGroupID), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProcessGroupComponent(response)
+}
+
+func newProcessGroupComponent(response string) (*Component, error) {
+	var output map[string]interface{}
+	err := json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	component, ok := output["component"].(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidFormat
+	}
+
+	return NewComponent(ProcessGroupName, "", component), nil
+}
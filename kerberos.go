@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+const DefaultKrb5Config = "/etc/krb5.conf"
+
+// KerberosConfig describes the Kerberos credentials. Without a keytab the
+// credential cache is used, which defaults to KRB5CCNAME or
+// /tmp/krb5cc_<uid>. The krb5.conf defaults to KRB5_CONFIG or
+// DefaultKrb5Config and the SPN to HTTP/<host of the server>.
+type KerberosConfig struct {
+	Krb5Config string `yaml:"krb5-config,omitempty"`
+	Principal  string `yaml:"principal,omitempty"`
+	Realm      string `yaml:"realm,omitempty"`
+	Keytab     string `yaml:"keytab,omitempty"`
+	CCache     string `yaml:"ccache,omitempty"`
+	SPN        string `yaml:"spn,omitempty"`
+}
+
+// LoginKerberos gets a token from /access/kerberos with SPNEGO, if a Kerberos
+// login identity provider is configured in NiFi. The token is cached for the
+// principal, so the KDC is only asked without a valid cached token.
+func LoginKerberos(server *url.URL, kerberos *KerberosConfig, options ...Option) (*Client, error) {
+	cl, err := kerberos.client()
+	if err != nil {
+		return nil, err
+	}
+
+	defer cl.Destroy()
+
+	username := cl.Credentials.UserName() + "@" + cl.Credentials.Domain()
+
+	return login(server, username, NewOptions(options...), func(u *url.URL) (*http.Request, error) {
+		err := cl.Login()
+		if err != nil {
+			return nil, fmt.Errorf("kerberos: %w", err)
+		}
+
+		u.Path = "/nifi-api/access/kerberos"
+
+		request, err := http.NewRequest("POST", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = spnego.SetSPNEGOHeader(cl, request, kerberos.SPN)
+		if err != nil {
+			return nil, fmt.Errorf("kerberos: %w", err)
+		}
+
+		return request, nil
+	})
+}
+
+func (k *KerberosConfig) client() (*krb5client.Client, error) {
+	file := k.Krb5Config
+	if len(file) == 0 {
+		file = os.Getenv("KRB5_CONFIG")
+	}
+
+	if len(file) == 0 {
+		file = DefaultKrb5Config
+	}
+
+	cfg, err := config.Load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(k.Keytab) == 0 {
+		ccache, err := credentials.LoadCCache(k.ccache())
+		if err != nil {
+			return nil, err
+		}
+
+		return krb5client.NewFromCCache(ccache, cfg, krb5client.DisablePAFXFAST(true))
+	}
+
+	kt, err := keytab.Load(k.Keytab)
+	if err != nil {
+		return nil, err
+	}
+
+	username := k.Principal
+	realm := k.Realm
+
+	if i := strings.LastIndex(username, "@"); i >= 0 {
+		username, realm = username[:i], username[i+1:]
+	}
+
+	if len(realm) == 0 {
+		realm = cfg.LibDefaults.DefaultRealm
+	}
+
+	if len(username) == 0 {
+		return nil, fmt.Errorf("kerberos: principal is missing")
+	}
+
+	return krb5client.NewWithKeytab(username, realm, kt, cfg, krb5client.DisablePAFXFAST(true)), nil
+}
+
+func (k *KerberosConfig) ccache() string {
+	if len(k.CCache) > 0 {
+		return k.CCache
+	}
+
+	if name := os.Getenv("KRB5CCNAME"); len(name) > 0 {
+		return strings.TrimPrefix(name, "FILE:")
+	}
+
+	return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+}
@@ -0,0 +1,494 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testRealm   = "TEST.GOKRB5"
+	testUser    = "testuser1"
+	testService = "HTTP/localhost"
+	testEType   = etypeID.AES256_CTS_HMAC_SHA1_96
+)
+
+// testKDC is a minimal KDC over TCP, which answers AS and TGS requests
+// without pre-authentication for the principals of its keytab.
+type testKDC struct {
+	keytab   *keytab.Keytab
+	listener net.Listener
+}
+
+func newTestKDC(t *testing.T, kt *keytab.Keytab) *testKDC {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kdc := &testKDC{
+		keytab:   kt,
+		listener: listener,
+	}
+
+	go kdc.serve()
+
+	return kdc
+}
+
+func (k *testKDC) Close() {
+	k.listener.Close()
+}
+
+func (k *testKDC) serve() {
+	for {
+		conn, err := k.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go k.handle(conn)
+	}
+}
+
+func (k *testKDC) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var size uint32
+	err := binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		return
+	}
+
+	request := make([]byte, size)
+	_, err = io.ReadFull(conn, request)
+	if err != nil {
+		return
+	}
+
+	var response []byte
+
+	// the application tag of the ASN.1 message is 10 for AS-REQ and 12 for
+	// TGS-REQ
+	switch request[0] & 0x1f {
+	case 10:
+		response, err = k.asReply(request)
+	case 12:
+		response, err = k.tgsReply(request)
+	default:
+		err = fmt.Errorf("unexpected message tag %v", request[0]&0x1f)
+	}
+
+	if err != nil {
+		return
+	}
+
+	binary.Write(conn, binary.BigEndian, uint32(len(response)))
+	conn.Write(response)
+}
+
+func (k *testKDC) asReply(data []byte) ([]byte, error) {
+	var request messages.ASReq
+	err := request.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, sessionKey, err := k.ticket(request.ReqBody.CName, request.ReqBody.SName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, kvno, err := k.keytab.GetEncryptionKey(request.ReqBody.CName, testRealm, 0, testEType)
+	if err != nil {
+		return nil, err
+	}
+
+	encPart, err := k.encPart(request.ReqBody.Nonce, request.ReqBody.SName, sessionKey, key, keyusage.AS_REP_ENCPART, kvno)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := messages.ASRep{
+		KDCRepFields: messages.KDCRepFields{
+			PVNO:    5,
+			MsgType: msgtype.KRB_AS_REP,
+			CRealm:  testRealm,
+			CName:   request.ReqBody.CName,
+			Ticket:  ticket,
+			EncPart: encPart,
+		},
+	}
+
+	return reply.Marshal()
+}
+
+func (k *testKDC) tgsReply(data []byte) ([]byte, error) {
+	var request messages.TGSReq
+	err := request.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var apReq messages.APReq
+	for _, pa := range request.PAData {
+		if pa.PADataType == patype.PA_TGS_REQ {
+			err = apReq.Unmarshal(pa.PADataValue)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = apReq.Ticket.DecryptEncPart(k.keytab, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tgt := apReq.Ticket.DecryptedEncPart
+
+	ticket, sessionKey, err := k.ticket(tgt.CName, request.ReqBody.SName)
+	if err != nil {
+		return nil, err
+	}
+
+	encPart, err := k.encPart(request.ReqBody.Nonce, request.ReqBody.SName, sessionKey, tgt.Key, keyusage.TGS_REP_ENCPART_SESSION_KEY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := messages.TGSRep{
+		KDCRepFields: messages.KDCRepFields{
+			PVNO:    5,
+			MsgType: msgtype.KRB_TGS_REP,
+			CRealm:  testRealm,
+			CName:   request.ReqBody.CName,
+			Ticket:  ticket,
+			EncPart: encPart,
+		},
+	}
+
+	return reply.Marshal()
+}
+
+func (k *testKDC) ticket(cname types.PrincipalName, sname types.PrincipalName) (messages.Ticket, types.EncryptionKey, error) {
+	now := time.Now().UTC()
+
+	return messages.NewTicket(cname, testRealm, sname, testRealm, types.NewKrbFlags(), k.keytab, testEType, 1, now, now, now.Add(time.Hour), now.Add(time.Hour))
+}
+
+func (k *testKDC) encPart(nonce int, sname types.PrincipalName, sessionKey types.EncryptionKey, key types.EncryptionKey, usage uint32, kvno int) (types.EncryptedData, error) {
+	now := time.Now().UTC()
+
+	part := messages.EncKDCRepPart{
+		Key:           sessionKey,
+		LastReqs:      []messages.LastReq{{LRValue: now}},
+		Nonce:         nonce,
+		KeyExpiration: now.Add(time.Hour),
+		Flags:         types.NewKrbFlags(),
+		AuthTime:      now,
+		StartTime:     now,
+		EndTime:       now.Add(time.Hour),
+		RenewTill:     now.Add(time.Hour),
+		SRealm:        testRealm,
+		SName:         sname,
+	}
+
+	data, err := part.Marshal()
+	if err != nil {
+		return types.EncryptedData{}, err
+	}
+
+	return crypto.GetEncryptedData(data, key, usage, kvno)
+}
+
+func newTestKeytab(t *testing.T, userPassword string) *keytab.Keytab {
+	kt := keytab.New()
+
+	entries := map[string]string{
+		testUser:              userPassword,
+		"krbtgt/" + testRealm: "krbtgt-secret",
+		testService:           "service-secret",
+	}
+
+	for principal, password := range entries {
+		err := kt.AddEntry(principal, testRealm, password, time.Now(), 1, testEType)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return kt
+}
+
+// newKerberosTestServer returns a NiFi stand-in, which issues a token with the
+// subject for SPNEGO requests to /access/kerberos.
+func newKerberosTestServer(t *testing.T, kt *keytab.Keytab, subject string) *httptest.Server {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("0123456789abcdef0123456789abcdef")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Subject: subject,
+			Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).CompactSerialize()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(token))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/nifi-api/access/kerberos", spnego.SPNEGOKRB5Authenticate(login, kt, service.DecodePAC(false)))
+	mux.HandleFunc("/nifi-api/access/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"config":{"supportsLogin":false}}`))
+	})
+	mux.HandleFunc("/nifi-api/access", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/nifi-api/process-groups/root", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"root-id","component":{"id":"root-id","name":"NiFi Flow"}}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func writeKerberosFiles(t *testing.T, kdc *testKDC, kt *keytab.Keytab) (string, string) {
+	dir := t.TempDir()
+
+	krb5conf := fmt.Sprintf(`[libdefaults]
+  default_realm = %[1]v
+  udp_preference_limit = 1
+  default_tkt_enctypes = aes256-cts-hmac-sha1-96
+  default_tgs_enctypes = aes256-cts-hmac-sha1-96
+  permitted_enctypes = aes256-cts-hmac-sha1-96
+
+[realms]
+  %[1]v = {
+    kdc = %[2]v
+  }
+`, testRealm, kdc.listener.Addr().String())
+
+	config := filepath.Join(dir, "krb5.conf")
+	err := ioutil.WriteFile(config, []byte(krb5conf), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := kt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keytabFile := filepath.Join(dir, "user.keytab")
+	err = ioutil.WriteFile(keytabFile, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config, keytabFile
+}
+
+func TestLoginKerberos(t *testing.T) {
+	kt := newTestKeytab(t, "user-secret")
+
+	kdc := newTestKDC(t, kt)
+	defer kdc.Close()
+
+	server := newKerberosTestServer(t, kt, testUser+"@"+testRealm)
+	defer server.Close()
+
+	config, keytabFile := writeKerberosFiles(t, kdc, kt)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := LoginKerberos(u, &KerberosConfig{
+		Krb5Config: config,
+		Principal:  testUser + "@" + testRealm,
+		Keytab:     keytabFile,
+		SPN:        testService,
+	}, WithoutTokenCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.status.User != testUser+"@"+testRealm {
+		t.Errorf("user = %v, want %v", client.status.User, testUser+"@"+testRealm)
+	}
+
+	if len(client.status.Token) == 0 {
+		t.Error("token is missing")
+	}
+}
+
+func TestLoginKerberosCachedToken(t *testing.T) {
+	kt := newTestKeytab(t, "user-secret")
+
+	kdc := newTestKDC(t, kt)
+
+	// NiFi maps the principal to the user name without realm.
+	server := newKerberosTestServer(t, kt, testUser)
+	defer server.Close()
+
+	config, keytabFile := writeKerberosFiles(t, kdc, kt)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kerberos := &KerberosConfig{
+		Krb5Config: config,
+		Principal:  testUser + "@" + testRealm,
+		Keytab:     keytabFile,
+		SPN:        testService,
+	}
+
+	store := NewMemoryTokenStore()
+
+	client, err := LoginKerberos(u, kerberos, WithTokenStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kdc.Close()
+
+	cached, err := LoginKerberos(u, kerberos, WithTokenStore(store))
+	if err != nil {
+		t.Fatalf("login with a cached token needs the KDC: %v", err)
+	}
+
+	if cached.status.Token != client.status.Token || cached.status.User != testUser {
+		t.Errorf("cached status = %v/%v, want %v/%v", cached.status.User, cached.status.Token, testUser, client.status.Token)
+	}
+}
+
+func TestLoginAutoKerberos(t *testing.T) {
+	kt := newTestKeytab(t, "user-secret")
+
+	kdc := newTestKDC(t, kt)
+	defer kdc.Close()
+
+	server := newKerberosTestServer(t, kt, testUser+"@"+testRealm)
+	defer server.Close()
+
+	config, keytabFile := writeKerberosFiles(t, kdc, kt)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := DetectAccess(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Method() != KerberosLogin {
+		t.Errorf("method = %v, want %v", info.Method(), KerberosLogin)
+	}
+
+	client, err := LoginAuto(u, "", "", WithKerberos(&KerberosConfig{
+		Krb5Config: config,
+		Principal:  testUser + "@" + testRealm,
+		Keytab:     keytabFile,
+		SPN:        testService,
+	}), WithoutTokenCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.status.Token) == 0 {
+		t.Error("token is missing")
+	}
+}
+
+func TestLoginKerberosWrongKey(t *testing.T) {
+	kt := newTestKeytab(t, "user-secret")
+
+	kdc := newTestKDC(t, kt)
+	defer kdc.Close()
+
+	server := newKerberosTestServer(t, kt, testUser+"@"+testRealm)
+	defer server.Close()
+
+	config, keytabFile := writeKerberosFiles(t, kdc, newTestKeytab(t, "wrong-secret"))
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoginKerberos(u, &KerberosConfig{
+		Krb5Config: config,
+		Principal:  testUser,
+		Keytab:     keytabFile,
+		SPN:        testService,
+	}, WithoutTokenCache())
+	if err == nil {
+		t.Fatal("login with a wrong key succeeded")
+	}
+}
+
+func TestKerberosConfigWithoutPrincipal(t *testing.T) {
+	kt := newTestKeytab(t, "user-secret")
+
+	kdc := newTestKDC(t, kt)
+	defer kdc.Close()
+
+	config, keytabFile := writeKerberosFiles(t, kdc, kt)
+
+	_, err := (&KerberosConfig{
+		Krb5Config: config,
+		Keytab:     keytabFile,
+	}).client()
+	if err == nil {
+		t.Fatal("client without principal created")
+	}
+}
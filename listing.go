@@ -35,6 +35,7 @@ type FlowFile struct {
 	QueuedStart     time.Time `json:"queuedStart"`
 	Penalized       bool      `json:"penalized"`
 	Node            string    `json:"node"`
+	NodeID          string    `json:"nodeId"`
 }
 
 type ListingRequest struct {
@@ -126,6 +127,11 @@ func (r *ListingRequest) List() ([]FlowFile, error) {
 				return true, nil
 			}
 
+			nodeId, _ := file["clusterNodeId"].(string)
+			if len(r.client.node) > 0 && nodeId != r.client.node {
+				return true, nil
+			}
+
 			size, ok := file["size"].(float64)
 			if !ok {
 				return true, nil
@@ -145,6 +151,7 @@ func (r *ListingRequest) List() ([]FlowFile, error) {
 				Uuid:            uuid,
 				Filename:        filename,
 				Node:            node,
+				NodeID:          nodeId,
 				Size:            int(size),
 				LinageStart:     now.Add(-time.Duration(lineageDuration) * time.Millisecond),
 				QueuedStart:     now.Add(-time.Duration(queuedDuration) * time.Millisecond),
@@ -0,0 +1,282 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DefaultExpiryDelta is the time before the expiry, when a token is refreshed.
+const DefaultExpiryDelta = 30 * time.Second
+
+// Token is a bearer token. A zero expiry means the token doesn't expire.
+type Token struct {
+	AccessToken string
+	Expiry      time.Time
+}
+
+func (t *Token) Valid(delta time.Duration) bool {
+	if t == nil || len(t.AccessToken) == 0 {
+		return false
+	}
+
+	return t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry)
+}
+
+// TokenSource provides bearer tokens like an oauth2.TokenSource, e.g. from an
+// OIDC provider like Keycloak in front of NiFi.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// ClientCredentials fetches tokens with the OAuth2 client credentials flow.
+// The IdP doesn't share the TLS config of NiFi, without a http client
+// http.DefaultClient is used, which trusts the system roots.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Client       *http.Client
+}
+
+// NewClientCredentials returns a token source, which caches the token and
+// refreshes it before the expiry. The client may be nil or a client of
+// NewTokenClient for an IdP with a private CA.
+func NewClientCredentials(client *http.Client, tokenURL string, clientID string, clientSecret string, scopes ...string) TokenSource {
+	return ReuseTokenSource(&ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		Client:       client,
+	}, DefaultExpiryDelta)
+}
+
+// NewTokenClient returns a http client for the token endpoint of an IdP,
+// which trusts the system roots and the CA certificates of the PEM file, if
+// it's set. It never sends a client certificate.
+func NewTokenClient(ca string) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if len(ca) > 0 {
+		certs, err := (&HttpClient{}).LoadPemCertificate(ca)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: pool,
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+func (c *ClientCredentials) httpClient() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+
+	return c.Client
+}
+
+func (c *ClientCredentials) Token() (*Token, error) {
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+
+	if len(c.Scopes) > 0 {
+		data.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	request, err := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	response, err := c.httpClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode > 299 {
+		return nil, fmt.Errorf("token: %v (%v)", string(body), response.Status)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.AccessToken) == 0 {
+		return nil, fmt.Errorf("token: access_token is missing")
+	}
+
+	if len(result.TokenType) > 0 && !strings.EqualFold(result.TokenType, "bearer") {
+		return nil, fmt.Errorf("token: unsupported token type %v", result.TokenType)
+	}
+
+	token := &Token{
+		AccessToken: result.AccessToken,
+	}
+
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// FileTokenSource reads a pre-issued token from a file and reads it again,
+// when the file has changed, e.g. a token mounted by a sidecar.
+type FileTokenSource struct {
+	Path string
+
+	mutex    sync.Mutex
+	token    *Token
+	modified time.Time
+}
+
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{
+		Path: path,
+	}
+}
+
+func (s *FileTokenSource) Token() (*Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.token != nil && !info.ModTime().After(s.modified) {
+		return s.token, nil
+	}
+
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &Token{
+		AccessToken: strings.TrimSpace(string(data)),
+	}
+
+	if len(token.AccessToken) == 0 {
+		return nil, fmt.Errorf("token file %v is empty", s.Path)
+	}
+
+	token.Expiry = tokenExpiry(token.AccessToken)
+
+	s.token = token
+	s.modified = info.ModTime()
+
+	return token, nil
+}
+
+// tokenExpiry reads the exp claim of a JWT without verification, opaque
+// tokens don't expire.
+func tokenExpiry(token string) time.Time {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims jwt.Claims
+	err = parsed.UnsafeClaimsWithoutVerification(&claims)
+	if err != nil || claims.Expiry == nil {
+		return time.Time{}
+	}
+
+	return claims.Expiry.Time()
+}
+
+type reuseTokenSource struct {
+	source TokenSource
+	delta  time.Duration
+
+	mutex sync.Mutex
+	token *Token
+}
+
+// ReuseTokenSource caches the token of the source until delta before the
+// expiry.
+func ReuseTokenSource(source TokenSource, delta time.Duration) TokenSource {
+	return &reuseTokenSource{
+		source: source,
+		delta:  delta,
+	}
+}
+
+func (s *reuseTokenSource) Token() (*Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token.Valid(s.delta) {
+		return s.token, nil
+	}
+
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.token = token
+
+	return token, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientCredentialsWithTokenClient(t *testing.T) {
+	idp := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			t.Error("client certificate sent to the IdP")
+		}
+
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "nifi" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token","token_type":"Bearer","expires_in":300}`))
+	}))
+	idp.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	idp.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	idp.StartTLS()
+	defer idp.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	err := ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewClientCredentials(nil, idp.URL, "nifi", "secret").Token()
+	if err == nil {
+		t.Fatal("IdP with an unknown CA trusted")
+	}
+
+	client, err := NewTokenClient(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := NewClientCredentials(client, idp.URL, "nifi", "secret").Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "token" || !token.Valid(time.Minute) {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// RetryPolicy retries idempotent requests on network errors and on the
+// status codes with an exponential backoff.
+type RetryPolicy struct {
+	MaxRetries  int
+	Backoff     time.Duration
+	StatusCodes []int
+}
+
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxRetries:  3,
+	Backoff:     500 * time.Millisecond,
+	StatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+type Options struct {
+	TLSConfig          *tls.Config
+	CA                 string
+	CAPool             *x509.CertPool
+	InsecureSkipVerify bool
+	Certificates       []tls.Certificate
+	PKCS12File         string
+	PKCS12Password     string
+	PEMCertFile        string
+	PEMKeyFile         string
+	PEMKeyPassword     string
+	TokenStore         TokenStore
+	TokenSource        TokenSource
+	TokenSkew          time.Duration
+	TokenAudience      string
+	TokenKeys          *jose.JSONWebKeySet
+	Kerberos           *KerberosConfig
+	Timeout            time.Duration
+	Retry              *RetryPolicy
+	UserAgent          string
+	Proxy              func(*http.Request) (*url.URL, error)
+	ProxyTLSConfig     *tls.Config
+	Transport          http.RoundTripper
+	DialTimeout        time.Duration
+	KeepAlive          time.Duration
+	HandshakeTimeout   time.Duration
+	IdleConnTimeout    time.Duration
+	MaxIdleConns       int
+	MaxConnsPerHost    int
+	DisableHTTP2       bool
+	Logger             Logger
+}
+
+type Option func(*Options)
+
+func NewOptions(options ...Option) *Options {
+	o := &Options{
+		TokenStore: NewFileTokenStore(DefaultTokenFile),
+		TokenSkew:  DefaultTokenSkew,
+	}
+
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
+
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = config
+	}
+}
+
+// WithCA adds the CA certificates of a PEM file to the trusted root CAs.
+func WithCA(file string) Option {
+	return func(o *Options) {
+		o.CA = file
+	}
+}
+
+func WithCAPool(pool *x509.CertPool) Option {
+	return func(o *Options) {
+		o.CAPool = pool
+	}
+}
+
+func WithInsecureSkipVerify(insecure bool) Option {
+	return func(o *Options) {
+		o.InsecureSkipVerify = insecure
+	}
+}
+
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *Options) {
+		o.Certificates = append(o.Certificates, cert)
+	}
+}
+
+func WithPKCS12(file string, password string) Option {
+	return func(o *Options) {
+		o.PKCS12File = file
+		o.PKCS12Password = password
+	}
+}
+
+// WithPEMKeyPair uses a certificate chain and a private key from PEM files as
+// client certificate. The password is only needed for encrypted PKCS#8 keys.
+// The files are reloaded on the next TLS handshake after they have changed.
+func WithPEMKeyPair(certFile string, keyFile string, password string) Option {
+	return func(o *Options) {
+		o.PEMCertFile = certFile
+		o.PEMKeyFile = keyFile
+		o.PEMKeyPassword = password
+	}
+}
+
+func WithTokenStore(store TokenStore) Option {
+	return func(o *Options) {
+		o.TokenStore = store
+	}
+}
+
+// WithTokenSource authenticates every request with a bearer token of the
+// source instead of a client certificate, e.g. with NewClientCredentials or
+// NewFileTokenSource.
+func WithTokenSource(source TokenSource) Option {
+	return func(o *Options) {
+		o.TokenSource = source
+	}
+}
+
+func WithTokenSkew(skew time.Duration) Option {
+	return func(o *Options) {
+		o.TokenSkew = skew
+	}
+}
+
+// WithTokenAudience only reuses cached tokens with the audience.
+func WithTokenAudience(audience string) Option {
+	return func(o *Options) {
+		o.TokenAudience = audience
+	}
+}
+
+// WithTokenVerification verifies the signature of cached tokens with the keys
+// before they are reused.
+func WithTokenVerification(keys *jose.JSONWebKeySet) Option {
+	return func(o *Options) {
+		o.TokenKeys = keys
+	}
+}
+
+// WithKerberos sets the Kerberos credentials used by LoginAuto.
+func WithKerberos(kerberos *KerberosConfig) Option {
+	return func(o *Options) {
+		o.Kerberos = kerberos
+	}
+}
+
+func (o *Options) kerberos() *KerberosConfig {
+	if o.Kerberos == nil {
+		return &KerberosConfig{}
+	}
+
+	return o.Kerberos
+}
+
+func WithoutTokenCache() Option {
+	return WithTokenStore(NoopTokenStore{})
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+func WithRetry(policy *RetryPolicy) Option {
+	return func(o *Options) {
+		o.Retry = policy
+	}
+}
+
+func WithUserAgent(agent string) Option {
+	return func(o *Options) {
+		o.UserAgent = agent
+	}
+}
+
+// WithProxy replaces the proxy of the environment variables HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY. Without WithProxyTLSConfig the TLS config incl. the
+// client certificate is used for HTTPS proxies too.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(o *Options) {
+		o.Proxy = proxy
+	}
+}
+
+func WithProxyURL(proxy *url.URL) Option {
+	return WithProxy(http.ProxyURL(proxy))
+}
+
+// WithProxyTLSConfig sets a separate TLS config for connections to HTTPS
+// proxies, so the proxy gets neither the CA nor the client certificate of
+// NiFi.
+func WithProxyTLSConfig(config *tls.Config) Option {
+	return func(o *Options) {
+		o.ProxyTLSConfig = config
+	}
+}
+
+// WithDialTimeout sets the timeout and the keep alive period of TCP
+// connections.
+func WithDialTimeout(timeout time.Duration, keepAlive time.Duration) Option {
+	return func(o *Options) {
+		o.DialTimeout = timeout
+		o.KeepAlive = keepAlive
+	}
+}
+
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HandshakeTimeout = timeout
+	}
+}
+
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.IdleConnTimeout = timeout
+	}
+}
+
+// WithMaxConns limits the idle connections in the pool and the connections
+// per host, 0 means no limit.
+func WithMaxConns(maxIdle int, maxPerHost int) Option {
+	return func(o *Options) {
+		o.MaxIdleConns = maxIdle
+		o.MaxConnsPerHost = maxPerHost
+	}
+}
+
+func WithoutHTTP2() Option {
+	return func(o *Options) {
+		o.DisableHTTP2 = true
+	}
+}
+
+// WithTransport replaces the transport, which defaults to a clone of
+// http.DefaultTransport. TLS, proxy and connection options are only applied,
+// if it's a *http.Transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *Options) {
+		o.Transport = transport
+	}
+}
+
+func WithLogger(logger Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// validateToken checks, if a cached token can be reused without a request.
+func (o *Options) validateToken(token string) error {
+	var info *TokenInfo
+	var err error
+
+	if o.TokenKeys != nil {
+		info, err = VerifyToken(token, o.TokenKeys)
+	} else {
+		info, err = InspectToken(token)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return info.Validate(o.TokenAudience, o.TokenSkew)
+}
+
+func (o *Options) logf(format string, v ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,375 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrPasswordRequired = fmt.Errorf("private key is encrypted, but no password is set")
+)
+
+// LoadPEMKeyPair reads a certificate chain and the private key from PEM files.
+// The key may be a PKCS#1, SEC 1 or PKCS#8 key, PKCS#8 keys can be encrypted
+// with PBES2.
+func LoadPEMKeyPair(certFile string, keyFile string, password string) (*tls.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePEMKeyPair(certPEM, keyPEM, password)
+}
+
+func ParsePEMKeyPair(certPEM []byte, keyPEM []byte, password string) (*tls.Certificate, error) {
+	cert := &tls.Certificate{}
+
+	for {
+		block, rest := pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+
+		certPEM = rest
+	}
+
+	if len(cert.Certificate) == 0 {
+		return nil, fmt.Errorf("client certificate is missing")
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	cert.Leaf = leaf
+
+	key, err := parsePEMPrivateKey(keyPEM, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkKeyPair(leaf, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert.PrivateKey = key
+
+	return cert, nil
+}
+
+func parsePEMPrivateKey(data []byte, password string) (crypto.PrivateKey, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("private key is missing")
+		}
+
+		switch block.Type {
+		case "ENCRYPTED PRIVATE KEY":
+			if len(password) == 0 {
+				return nil, ErrPasswordRequired
+			}
+
+			der, err := decryptPKCS8(block.Bytes, password)
+			if err != nil {
+				return nil, err
+			}
+
+			return x509.ParsePKCS8PrivateKey(der)
+
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		}
+
+		data = rest
+	}
+}
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+func decryptPKCS8(der []byte, password string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	_, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption: %v", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	_, err = asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	_, err = asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA384):
+		prf = sha512.New384
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported pseudo random function: %v", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+
+	scheme := params.EncryptionScheme.Algorithm
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme: %v", scheme)
+	}
+
+	var iv []byte
+	_, err = asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv)
+	if err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key([]byte(password), kdf.Salt, kdf.IterationCount, keyLen, prf)
+
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := info.EncryptedData
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 || len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid encrypted private key")
+	}
+
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("can't decrypt private key: wrong password")
+	}
+
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+func checkKeyPair(leaf *x509.Certificate, privateKey crypto.PrivateKey) error {
+	switch pub := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		priv, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return errors.New("tls: private key type does not match public key type")
+		}
+		if pub.N.Cmp(priv.N) != 0 {
+			return errors.New("tls: private key does not match public key")
+		}
+	case *ecdsa.PublicKey:
+		priv, ok := privateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return errors.New("tls: private key type does not match public key type")
+		}
+		if pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
+			return errors.New("tls: private key does not match public key")
+		}
+	case ed25519.PublicKey:
+		priv, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return errors.New("tls: private key type does not match public key type")
+		}
+		if !bytes.Equal(priv.Public().(ed25519.PublicKey), pub) {
+			return errors.New("tls: private key does not match public key")
+		}
+	default:
+		return errors.New("tls: unknown public key algorithm")
+	}
+
+	return nil
+}
+
+// CertificateReloader provides a PEM client certificate for TLS handshakes
+// and reloads it, when the certificate or key file changes on disk, e.g.
+// after a rotation by cert-manager.
+type CertificateReloader struct {
+	CertFile string
+	KeyFile  string
+	Password string
+
+	mutex    sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func NewCertificateReloader(certFile string, keyFile string, password string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		Password: password,
+	}
+
+	_, err := r.Certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Certificate returns the current certificate. If the files can't be loaded
+// after a change, the last valid certificate is kept.
+func (r *CertificateReloader) Certificate() (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	modified, err := r.lastModified()
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+
+		return nil, err
+	}
+
+	if r.cert != nil && !modified.After(r.modified) {
+		return r.cert, nil
+	}
+
+	cert, err := LoadPEMKeyPair(r.CertFile, r.KeyFile, r.Password)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+
+		return nil, err
+	}
+
+	r.cert = cert
+	r.modified = modified
+
+	return cert, nil
+}
+
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate()
+}
+
+func (r *CertificateReloader) lastModified() (time.Time, error) {
+	var result time.Time
+
+	for _, file := range []string{r.CertFile, r.KeyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return result, err
+		}
+
+		if info.ModTime().After(result) {
+			result = info.ModTime()
+		}
+	}
+
+	return result, nil
+}
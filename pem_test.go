@@ -0,0 +1,104 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata are generated with openssl, e.g.
+//
+//	openssl pkcs8 -topk8 -in rsa.key -out rsa-aes256.key -v2 aes256 -v2prf hmacWithSHA256
+//	openssl pkcs8 -topk8 -in rsa.key -out rsa-des3.key -v2 des3 -v2prf hmacWithSHA1
+//	openssl pkcs8 -topk8 -in ec.key -out ec-aes256.key -v2 aes256 -v2prf hmacWithSHA512
+//
+// with the password "secret".
+func TestLoadPEMKeyPair(t *testing.T) {
+	tests := []struct {
+		name     string
+		cert     string
+		key      string
+		password string
+		ec       bool
+	}{
+		{name: "rsa", cert: "rsa.crt", key: "rsa.key"},
+		{name: "rsa aes-256 sha-256", cert: "rsa.crt", key: "rsa-aes256.key", password: "secret"},
+		{name: "rsa 3des sha-1", cert: "rsa.crt", key: "rsa-des3.key", password: "secret"},
+		{name: "ec", cert: "ec.crt", key: "ec.key", ec: true},
+		{name: "ec aes-256 sha-512", cert: "ec.crt", key: "ec-aes256.key", password: "secret", ec: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert, err := LoadPEMKeyPair(filepath.Join("testdata", test.cert), filepath.Join("testdata", test.key), test.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "nifi-client" {
+				t.Errorf("unexpected certificate: %v", cert.Leaf)
+			}
+
+			switch cert.PrivateKey.(type) {
+			case *rsa.PrivateKey:
+				if test.ec {
+					t.Error("rsa key, want ec key")
+				}
+			case *ecdsa.PrivateKey:
+				if !test.ec {
+					t.Error("ec key, want rsa key")
+				}
+			default:
+				t.Errorf("unexpected key type %T", cert.PrivateKey)
+			}
+		})
+	}
+}
+
+func TestLoadPEMKeyPairWrongPassword(t *testing.T) {
+	for _, key := range []string{"rsa-aes256.key", "rsa-des3.key", "ec-aes256.key"} {
+		t.Run(key, func(t *testing.T) {
+			cert := "rsa.crt"
+			if key == "ec-aes256.key" {
+				cert = "ec.crt"
+			}
+
+			_, err := LoadPEMKeyPair(filepath.Join("testdata", cert), filepath.Join("testdata", key), "wrong")
+			if err == nil {
+				t.Fatal("key decrypted with a wrong password")
+			}
+		})
+	}
+}
+
+func TestLoadPEMKeyPairWithoutPassword(t *testing.T) {
+	_, err := LoadPEMKeyPair(filepath.Join("testdata", "rsa.crt"), filepath.Join("testdata", "rsa-aes256.key"), "")
+	if !errors.Is(err, ErrPasswordRequired) {
+		t.Fatalf("err = %v, want %v", err, ErrPasswordRequired)
+	}
+}
+
+func TestLoadPEMKeyPairMismatch(t *testing.T) {
+	_, err := LoadPEMKeyPair(filepath.Join("testdata", "rsa.crt"), filepath.Join("testdata", "ec.key"), "")
+	if err == nil {
+		t.Fatal("key pair with a foreign key loaded")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	ErrPermissionDenied = fmt.Errorf("permission denied")
+)
+
+type Permissions struct {
+	CanRead  bool `json:"canRead"`
+	CanWrite bool `json:"canWrite"`
+}
+
+type CurrentUser struct {
+	Identity             string      `json:"identity"`
+	Anonymous            bool        `json:"anonymous"`
+	CanVersionFlows      bool        `json:"canVersionFlows"`
+	Controller           Permissions `json:"controllerPermissions"`
+	Policies             Permissions `json:"policiesPermissions"`
+	Tenants              Permissions `json:"tenantsPermissions"`
+	Provenance           Permissions `json:"provenancePermissions"`
+	Counters             Permissions `json:"countersPermissions"`
+	System               Permissions `json:"systemPermissions"`
+	ParameterContexts    Permissions `json:"parameterContextPermissions"`
+	RestrictedComponents Permissions `json:"restrictedComponentsPermissions"`
+}
+
+func (c *Client) CurrentUser() (*CurrentUser, error) {
+	response, err := c.Get("/flow/current-user")
+	if err != nil {
+		return nil, err
+	}
+
+	var user CurrentUser
+	err = json.Unmarshal([]byte(response), &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ComponentPermissions returns the permissions and the operate permissions of
+// the current user for the component.
+func (c *Client) ComponentPermissions(component *Component) (*Permissions, *Permissions, error) {
+	entity, err := c.GetEntity(component.Type, component.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	permissions := newPermissions(entity["permissions"])
+	operate := newPermissions(entity["operatePermissions"])
+
+	return permissions, operate, nil
+}
+
+func (c *Client) CanRead(component *Component) (bool, error) {
+	permissions, _, err := c.ComponentPermissions(component)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.CanRead, nil
+}
+
+func (c *Client) CanWrite(component *Component) (bool, error) {
+	permissions, _, err := c.ComponentPermissions(component)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.CanWrite, nil
+}
+
+// CanOperate checks, if the current user can start and stop the component,
+// which is granted by write or operate permissions.
+func (c *Client) CanOperate(component *Component) (bool, error) {
+	permissions, operate, err := c.ComponentPermissions(component)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.CanWrite || operate.CanWrite, nil
+}
+
+func (c *Client) RequireWrite(component *Component) error {
+	ok, err := c.CanWrite(component)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("write %v: %w", component, ErrPermissionDenied)
+	}
+
+	return nil
+}
+
+func (c *Client) RequireOperate(component *Component) error {
+	ok, err := c.CanOperate(component)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("operate %v: %w", component, ErrPermissionDenied)
+	}
+
+	return nil
+}
+
+func newPermissions(data interface{}) *Permissions {
+	result := &Permissions{}
+
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return result
+	}
+
+	result.CanRead, _ = m["canRead"].(bool)
+	result.CanWrite, _ = m["canWrite"].(bool)
+
+	return result
+}
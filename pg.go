@@ -43,6 +43,26 @@ func (c *Client) GetInfo(id string) (interface{}, error) {
 	return output, nil
 }
 
+func (c *Client) GetEntity(t NiFiType, id string) (map[string]interface{}, error) {
+	resource := t.Resource()
+	if len(resource) == 0 {
+		return nil, fmt.Errorf("unsupported component type: %v", t)
+	}
+
+	response, err := c.CallAPI(Get, fmt.Sprintf("/%v/%v", resource, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var output map[string]interface{}
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
 func (c *Client) SetState(id string, state string) (string, error) {
 
 	body := &RunningStatus{
@@ -0,0 +1,323 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ReadAction  = "read"
+	WriteAction = "write"
+)
+
+type Access byte
+
+const (
+	ReadAccess    Access = 1 << iota
+	WriteAccess   Access = 1 << iota
+	OperateAccess Access = 1 << iota
+)
+
+type AccessPolicy struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	Users      []string  `json:"users"`
+	UserGroups []string  `json:"userGroups"`
+	Revision   *Revision `json:"-"`
+}
+
+type policyComponent struct {
+	ID         string            `json:"id,omitempty"`
+	Action     string            `json:"action"`
+	Resource   string            `json:"resource"`
+	Users      []tenantReference `json:"users"`
+	UserGroups []tenantReference `json:"userGroups"`
+}
+
+type policyEntity struct {
+	ID        string           `json:"id,omitempty"`
+	Revision  *Revision        `json:"revision"`
+	Component *policyComponent `json:"component"`
+}
+
+func (e *policyEntity) policy() *AccessPolicy {
+	return &AccessPolicy{
+		ID:         e.Component.ID,
+		Action:     e.Component.Action,
+		Resource:   e.Component.Resource,
+		Users:      tenantIds(e.Component.Users),
+		UserGroups: tenantIds(e.Component.UserGroups),
+		Revision:   e.Revision,
+	}
+}
+
+// GetPolicy returns the policy for exactly this action and resource. NiFi
+// answers with an inherited policy, if the resource hasn't an own one, which
+// is reported as ErrNotFound.
+func (c *Client) GetPolicy(action string, resource string) (*AccessPolicy, error) {
+	resource = policyPath(resource)
+
+	policy, err := c.EffectivePolicy(action, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.Resource != resource {
+		return nil, fmt.Errorf("policy %v %v: %w", action, resource, ErrNotFound)
+	}
+
+	return policy, nil
+}
+
+// EffectivePolicy returns the policy, which applies to the action and
+// resource. It's inherited from a parent resource, if the resource hasn't an
+// own policy.
+func (c *Client) EffectivePolicy(action string, resource string) (*AccessPolicy, error) {
+	resource = policyPath(resource)
+
+	response, err := c.Get(fmt.Sprintf("/policies/%v%v", action, resource))
+	if IsStatus(err, http.StatusNotFound) {
+		return nil, fmt.Errorf("policy %v %v: %w", action, resource, ErrNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var output policyEntity
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Component == nil {
+		return nil, fmt.Errorf("policy %v %v: %w", action, resource, ErrNotFound)
+	}
+
+	return output.policy(), nil
+}
+
+func (c *Client) CreatePolicy(action string, resource string, users []string, userGroups []string) (*AccessPolicy, error) {
+	return c.savePolicy(Post, "/policies", &policyEntity{
+		Revision: newRevisionZero(),
+		Component: &policyComponent{
+			Action:     action,
+			Resource:   policyPath(resource),
+			Users:      newTenantReferences(users),
+			UserGroups: newTenantReferences(userGroups),
+		},
+	})
+}
+
+func (c *Client) UpdatePolicy(policy *AccessPolicy) (*AccessPolicy, error) {
+	return c.savePolicy(Put, "/policies/"+policy.ID, &policyEntity{
+		ID:       policy.ID,
+		Revision: policy.Revision,
+		Component: &policyComponent{
+			ID:         policy.ID,
+			Action:     policy.Action,
+			Resource:   policy.Resource,
+			Users:      newTenantReferences(policy.Users),
+			UserGroups: newTenantReferences(policy.UserGroups),
+		},
+	})
+}
+
+func (c *Client) DeletePolicy(policy *AccessPolicy) error {
+	return c.deleteWithRevision("/policies/"+policy.ID, policy.Revision)
+}
+
+// AddTenants adds users and user groups to the policy. If the resource hasn't
+// an own policy yet, it's created with the tenants of the inherited policy,
+// so nobody loses the inherited access.
+func (c *Client) AddTenants(action string, resource string, users []string, userGroups []string) (*AccessPolicy, error) {
+	policy, err := c.GetPolicy(action, resource)
+	if errors.Is(err, ErrNotFound) {
+		return c.overridePolicy(action, resource, func(u []string, g []string) ([]string, []string) {
+			return union(u, users), union(g, userGroups)
+		})
+	} else if err != nil {
+		return nil, err
+	}
+
+	u := union(policy.Users, users)
+	g := union(policy.UserGroups, userGroups)
+
+	if len(u) == len(policy.Users) && len(g) == len(policy.UserGroups) {
+		return policy, nil
+	}
+
+	policy.Users = u
+	policy.UserGroups = g
+
+	return c.UpdatePolicy(policy)
+}
+
+// RemoveTenants removes users and user groups from the policy. If the resource
+// hasn't an own policy, the inherited policy is copied without the tenants.
+func (c *Client) RemoveTenants(action string, resource string, users []string, userGroups []string) (*AccessPolicy, error) {
+	policy, err := c.GetPolicy(action, resource)
+	if errors.Is(err, ErrNotFound) {
+		return c.overridePolicy(action, resource, func(u []string, g []string) ([]string, []string) {
+			return without(u, users), without(g, userGroups)
+		})
+	} else if err != nil {
+		return nil, err
+	}
+
+	u := without(policy.Users, users)
+	g := without(policy.UserGroups, userGroups)
+
+	if len(u) == len(policy.Users) && len(g) == len(policy.UserGroups) {
+		return policy, nil
+	}
+
+	policy.Users = u
+	policy.UserGroups = g
+
+	return c.UpdatePolicy(policy)
+}
+
+// overridePolicy creates an own policy for the resource from the tenants of
+// the inherited policy, like "copy policy" in the NiFi UI. Without an
+// inherited policy it starts empty. An unchanged inherited policy is returned
+// without creating an override and an empty one isn't created at all.
+func (c *Client) overridePolicy(action string, resource string, change func(users []string, userGroups []string) ([]string, []string)) (*AccessPolicy, error) {
+	var users, userGroups []string
+
+	inherited, err := c.EffectivePolicy(action, resource)
+	if err == nil {
+		users, userGroups = inherited.Users, inherited.UserGroups
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	u, g := change(users, userGroups)
+
+	if inherited != nil && sameSet(u, users) && sameSet(g, userGroups) {
+		return inherited, nil
+	}
+
+	if inherited == nil && len(u) == 0 && len(g) == 0 {
+		return nil, fmt.Errorf("policy %v %v: %w", action, policyPath(resource), ErrNotFound)
+	}
+
+	return c.CreatePolicy(action, resource, u, g)
+}
+
+// GrantProcessGroup grants a user group access to a process group. Read and
+// write include the data and provenance resources of the group.
+func (c *Client) GrantProcessGroup(groupID string, userGroupID string, access Access) error {
+	grants := map[string][]string{}
+
+	if access&ReadAccess > 0 {
+		grants[ReadAction] = append(grants[ReadAction],
+			"/process-groups/"+groupID,
+			"/data/process-groups/"+groupID,
+			"/provenance-data/process-groups/"+groupID,
+		)
+	}
+
+	if access&WriteAccess > 0 {
+		grants[WriteAction] = append(grants[WriteAction],
+			"/process-groups/"+groupID,
+			"/data/process-groups/"+groupID,
+		)
+	}
+
+	if access&OperateAccess > 0 {
+		grants[WriteAction] = append(grants[WriteAction],
+			"/operation/process-groups/"+groupID,
+		)
+	}
+
+	for action, resources := range grants {
+		for _, resource := range resources {
+			_, err := c.AddTenants(action, resource, nil, []string{userGroupID})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// policyPath returns the resource with a leading slash, as it's stored by NiFi.
+func policyPath(resource string) string {
+	return "/" + strings.TrimPrefix(resource, "/")
+}
+
+func union(a []string, b []string) []string {
+	result := append([]string{}, a...)
+	for _, v := range b {
+		if !contains(result, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func without(a []string, b []string) []string {
+	result := []string{}
+	for _, v := range a {
+		if !contains(b, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Client) savePolicy(method Method, path string, entity *policyEntity) (*AccessPolicy, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.CallAPI(method, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output policyEntity
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Component == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	return output.policy(), nil
+}
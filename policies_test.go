@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// newPolicyServer serves an inherited write policy of the root group for
+// every resource and records the created policies.
+func newPolicyServer(t *testing.T, users []string, created *[]policyComponent) *Client {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/nifi-api/policies/", func(w http.ResponseWriter, r *http.Request) {
+		if users == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&policyEntity{
+			Revision: &Revision{Version: 1},
+			Component: &policyComponent{
+				ID:       "inherited",
+				Action:   WriteAction,
+				Resource: "/process-groups/root-id",
+				Users:    newTenantReferences(users),
+			},
+		})
+	})
+
+	mux.HandleFunc("/nifi-api/policies", func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var entity policyEntity
+		err = json.Unmarshal(data, &entity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		*created = append(*created, *entity.Component)
+
+		entity.Component.ID = "created"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&entity)
+	})
+
+	return newTestClient(t, mux)
+}
+
+func TestAddTenantsCopiesInheritedPolicy(t *testing.T) {
+	var created []policyComponent
+	client := newPolicyServer(t, []string{"admin"}, &created)
+
+	policy, err := client.AddTenants(WriteAction, "process-groups/pg-id", nil, []string{"team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 1 {
+		t.Fatalf("created %v policies, want 1", len(created))
+	}
+
+	if created[0].Resource != "/process-groups/pg-id" {
+		t.Errorf("resource = %v, want /process-groups/pg-id", created[0].Resource)
+	}
+
+	if !sameSet(policy.Users, []string{"admin"}) || !sameSet(policy.UserGroups, []string{"team"}) {
+		t.Errorf("tenants = %v %v, want [admin] [team]", policy.Users, policy.UserGroups)
+	}
+}
+
+func TestAddTenantsWithoutInheritedPolicy(t *testing.T) {
+	var created []policyComponent
+	client := newPolicyServer(t, nil, &created)
+
+	policy, err := client.AddTenants(WriteAction, "/process-groups/pg-id", nil, []string{"team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 1 || len(policy.Users) != 0 || !sameSet(policy.UserGroups, []string{"team"}) {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+}
+
+func TestRemoveTenantsOverridesInheritedPolicy(t *testing.T) {
+	var created []policyComponent
+	client := newPolicyServer(t, []string{"admin", "guest"}, &created)
+
+	policy, err := client.RemoveTenants(WriteAction, "/process-groups/pg-id", []string{"guest"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 1 || !sameSet(policy.Users, []string{"admin"}) {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+
+	created = nil
+	_, err = client.RemoveTenants(WriteAction, "/process-groups/pg-id", []string{"unknown"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 0 {
+		t.Errorf("override created without a change")
+	}
+}
+
+func TestRemoveTenantsWithoutPolicy(t *testing.T) {
+	var created []policyComponent
+	client := newPolicyServer(t, nil, &created)
+
+	_, err := client.RemoveTenants(WriteAction, "/process-groups/pg-id", []string{"guest"}, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound  = fmt.Errorf("not found")
+	ErrAmbiguous = fmt.Errorf("ambiguous name")
+)
+
+type RegistryClient struct {
+	ID          string    `json:"id,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	URI         string    `json:"uri,omitempty"`
+	Revision    *Revision `json:"-"`
+}
+
+type Bucket struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type VersionedFlow struct {
+	Registry    string `json:"registryId"`
+	Bucket      string `json:"bucketId"`
+	ID          string `json:"flowId"`
+	Name        string `json:"flowName"`
+	Description string `json:"description"`
+}
+
+type registryClientEntity struct {
+	ID        string          `json:"id,omitempty"`
+	Revision  *Revision       `json:"revision"`
+	Component *RegistryClient `json:"component"`
+}
+
+func (e *registryClientEntity) registryClient() *RegistryClient {
+	rc := e.Component
+	if rc == nil {
+		rc = &RegistryClient{ID: e.ID}
+	}
+
+	rc.Revision = e.Revision
+	return rc
+}
+
+func (c *Client) RegistryClients() ([]*RegistryClient, error) {
+	response, err := c.Get("/controller/registry-clients")
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Registries []*registryClientEntity `json:"registries"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*RegistryClient{}
+	for _, e := range output.Registries {
+		result = append(result, e.registryClient())
+	}
+
+	return result, nil
+}
+
+func (c *Client) CreateRegistryClient(name string, uri string, description string) (*RegistryClient, error) {
+	entity := &registryClientEntity{
+		Revision: newRevisionZero(),
+		Component: &RegistryClient{
+			Name:        name,
+			URI:         uri,
+			Description: description,
+		},
+	}
+
+	return c.saveRegistryClient(Post, "/controller/registry-clients", entity)
+}
+
+func (c *Client) UpdateRegistryClient(registry *RegistryClient) (*RegistryClient, error) {
+	if registry.Revision == nil {
+		return nil, fmt.Errorf("registry client revision is missing")
+	}
+
+	entity := &registryClientEntity{
+		ID:        registry.ID,
+		Revision:  registry.Revision,
+		Component: registry,
+	}
+
+	return c.saveRegistryClient(Put, "/controller/registry-clients/"+registry.ID, entity)
+}
+
+func (c *Client) DeleteRegistryClient(registry *RegistryClient) error {
+	return c.deleteWithRevision("/controller/registry-clients/"+registry.ID, registry.Revision)
+}
+
+func (c *Client) saveRegistryClient(method Method, path string, entity *registryClientEntity) (*RegistryClient, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.CallAPI(method, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output registryClientEntity
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.registryClient(), nil
+}
+
+func (c *Client) Buckets(registry string) ([]*Bucket, error) {
+	response, err := c.Get(fmt.Sprintf("/flow/registries/%v/buckets", registry))
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Buckets []struct {
+			ID     string  `json:"id"`
+			Bucket *Bucket `json:"bucket"`
+		} `json:"buckets"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*Bucket{}
+	for _, e := range output.Buckets {
+		if e.Bucket != nil {
+			result = append(result, e.Bucket)
+		} else {
+			result = append(result, &Bucket{ID: e.ID})
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) Flows(registry string, bucket string) ([]*VersionedFlow, error) {
+	response, err := c.Get(fmt.Sprintf("/flow/registries/%v/buckets/%v/flows", registry, bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		VersionedFlows []struct {
+			VersionedFlow *VersionedFlow `json:"versionedFlow"`
+		} `json:"versionedFlows"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*VersionedFlow{}
+	for _, e := range output.VersionedFlows {
+		if e.VersionedFlow != nil {
+			result = append(result, e.VersionedFlow)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) ResolveRegistry(registry string) (string, error) {
+	list, err := c.RegistryClients()
+	if err != nil {
+		return "", err
+	}
+
+	ids := []string{}
+	for _, r := range list {
+		if r.ID == registry {
+			return r.ID, nil
+		}
+
+		if r.Name == registry {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	return single("registry", registry, ids)
+}
+
+func (c *Client) ResolveBucket(registry string, bucket string) (string, error) {
+	list, err := c.Buckets(registry)
+	if err != nil {
+		return "", err
+	}
+
+	ids := []string{}
+	for _, b := range list {
+		if b.ID == bucket {
+			return b.ID, nil
+		}
+
+		if b.Name == bucket {
+			ids = append(ids, b.ID)
+		}
+	}
+
+	return single("bucket", bucket, ids)
+}
+
+// ResolveFlow accepts names or ids for registry, bucket and flow and returns
+// the matching ids. A value is only resolved as name, if there is no item with
+// this id, so names may look like ids.
+func (c *Client) ResolveFlow(registry string, bucket string, flow string) (string, string, string, error) {
+	registryId, err := c.ResolveRegistry(registry)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	bucketId, err := c.ResolveBucket(registryId, bucket)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	list, err := c.Flows(registryId, bucketId)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	ids := []string{}
+	for _, f := range list {
+		if f.ID == flow {
+			return registryId, bucketId, f.ID, nil
+		}
+
+		if f.Name == flow {
+			ids = append(ids, f.ID)
+		}
+	}
+
+	flowId, err := single("flow", flow, ids)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return registryId, bucketId, flowId, nil
+}
+
+func single(kind string, name string, ids []string) (string, error) {
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%v %v: %w", kind, name, ErrNotFound)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%v %v: %w", kind, name, ErrAmbiguous)
+	}
+}
+
+// isUUID only accepts the canonical form, uuid.Parse accepts urn:uuid:,
+// braced and dash-less forms too.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	_, err := uuid.Parse(s)
+	return err == nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestIsUUID(t *testing.T) {
+	tests := map[string]bool{
+		"6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0001":          true,
+		"6f0a3e8e0d5c4d4e9a433f1b4c1d0001":              false,
+		"{6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0001}":        false,
+		"urn:uuid:6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0001": false,
+		"flow": false,
+	}
+
+	for val, expected := range tests {
+		if isUUID(val) != expected {
+			t.Errorf("isUUID(%v) = %v, want %v", val, !expected, expected)
+		}
+	}
+}
+
+func TestResolveFlowByNameLikeID(t *testing.T) {
+	mux := http.NewServeMux()
+
+	json := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+
+	mux.HandleFunc("/nifi-api/controller/registry-clients", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"registries":[{"id":"r1","component":{"id":"r1","name":"registry"}}]}`)
+	})
+
+	mux.HandleFunc("/nifi-api/flow/registries/r1/buckets", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"buckets":[{"id":"b1","bucket":{"id":"b1","name":"6f0a3e8e0d5c4d4e9a433f1b4c1d0001"}}]}`)
+	})
+
+	mux.HandleFunc("/nifi-api/flow/registries/r1/buckets/b1/flows", func(w http.ResponseWriter, r *http.Request) {
+		json(w, `{"versionedFlows":[
+			{"versionedFlow":{"flowId":"f1","flowName":"6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0002"}},
+			{"versionedFlow":{"flowId":"6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0003","flowName":"other"}}
+		]}`)
+	})
+
+	client := newTestClient(t, mux)
+
+	tests := []struct {
+		registry, bucket, flow string
+		expected               [3]string
+	}{
+		{"registry", "6f0a3e8e0d5c4d4e9a433f1b4c1d0001", "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0002", [3]string{"r1", "b1", "f1"}},
+		{"r1", "b1", "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0003", [3]string{"r1", "b1", "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0003"}},
+	}
+
+	for _, tt := range tests {
+		registry, bucket, flow, err := client.ResolveFlow(tt.registry, tt.bucket, tt.flow)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if [3]string{registry, bucket, flow} != tt.expected {
+			t.Errorf("ResolveFlow(%v, %v, %v) = %v, %v, %v, want %v", tt.registry, tt.bucket, tt.flow, registry, bucket, flow, tt.expected)
+		}
+	}
+
+	_, _, _, err := client.ResolveFlow("registry", "b1", "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0009")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrClusterUnhealthy = fmt.Errorf("cluster unhealthy")
+)
+
+const (
+	// DefaultNodeTimeout limits each step of the restart of a node, if the
+	// options have no timeout.
+	DefaultNodeTimeout = 15 * time.Minute
+	// DefaultRejoinGracePeriod is the time a restarted node gets to rejoin
+	// the cluster by itself, before the connect is requested.
+	DefaultRejoinGracePeriod = 2 * time.Minute
+)
+
+type RestartHook func(node *ClusterNode) error
+
+type RollingRestartOptions struct {
+	Restart     RestartHook
+	Timeout     time.Duration
+	GracePeriod time.Duration
+	Progress    func(node *ClusterNode, step string)
+}
+
+// RollingRestart restarts the cluster node by node. Each node is disconnected,
+// offloaded, restarted by the hook and reconnected. Each step is limited by
+// the timeout, which defaults to DefaultNodeTimeout. A restarted node gets the
+// grace period to rejoin by itself, before the connect is requested. Nodes
+// with a role (primary or coordinator) are handled last and the restart
+// aborts as soon as another node isn't connected. The node, which is used by
+// the client, is restarted last and managed through another connected node.
+// If the hook fails, the node is reconnected before the error is returned.
+func (c *Client) RollingRestart(options *RollingRestartOptions) error {
+	if options == nil || options.Restart == nil {
+		return fmt.Errorf("rolling restart: restart hook is missing")
+	}
+
+	o := *options
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultNodeTimeout
+	}
+
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = DefaultRejoinGracePeriod
+	}
+
+	nodes, err := c.ClusterNodes()
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if c.isServer(nodes[i]) != c.isServer(nodes[j]) {
+			return !c.isServer(nodes[i])
+		}
+
+		return len(nodes[i].Roles) < len(nodes[j].Roles)
+	})
+
+	for _, node := range nodes {
+		client := c
+		if c.isServer(node) {
+			client, err = c.via(nodes, node)
+			if err != nil {
+				return fmt.Errorf("rolling restart of %v: %w", node, err)
+			}
+		}
+
+		err := client.restartNode(node, &o)
+		if err != nil {
+			return fmt.Errorf("rolling restart of %v: %w", node, err)
+		}
+	}
+
+	return nil
+}
+
+// isServer checks, if the client sends its requests to the node.
+func (c *Client) isServer(node *ClusterNode) bool {
+	port := c.server.Port()
+	if len(port) == 0 {
+		port = "443"
+		if c.server.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return strings.EqualFold(c.server.Hostname(), node.Address) && port == strconv.Itoa(node.ApiPort)
+}
+
+// via returns a copy of the client, which sends its requests to another
+// connected node than the excluded one.
+func (c *Client) via(nodes []*ClusterNode, exclude *ClusterNode) (*Client, error) {
+	for _, n := range nodes {
+		if n.ID == exclude.ID || n.Status != CONNECTED {
+			continue
+		}
+
+		server := *c.server
+		server.Host = net.JoinHostPort(n.Address, strconv.Itoa(n.ApiPort))
+
+		rc := *c
+		rc.server = &server
+
+		return &rc, nil
+	}
+
+	return nil, fmt.Errorf("no other connected node: %w", ErrClusterUnhealthy)
+}
+
+func (c *Client) restartNode(node *ClusterNode, options *RollingRestartOptions) error {
+	progress := func(step string) {
+		if options.Progress != nil {
+			options.Progress(node, step)
+		}
+	}
+
+	err := c.checkClusterHealth("")
+	if err != nil {
+		return err
+	}
+
+	progress(DISCONNECTING)
+	node, err = c.DisconnectNode(node.ID, options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	progress(OFFLOADING)
+	node, err = c.OffloadNode(node.ID, options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	progress("RESTARTING")
+	err = options.Restart(node)
+	if err != nil {
+		_, rerr := c.reconnectNode(node, options.Timeout, 0)
+		if rerr != nil {
+			return fmt.Errorf("%w (reconnect: %v)", err, rerr)
+		}
+
+		return err
+	}
+
+	progress(CONNECTING)
+	_, err = c.reconnectNode(node, options.Timeout, options.GracePeriod)
+	if err != nil {
+		return err
+	}
+
+	progress(CONNECTED)
+
+	return c.checkClusterHealth("")
+}
+
+// reconnectNode waits until the node is connected. The connect is only
+// requested, if the node doesn't rejoin the cluster by itself within the grace
+// period or if it's started again, but still disconnected. Transient errors
+// are retried until the timeout.
+func (c *Client) reconnectNode(before *ClusterNode, timeout time.Duration, grace time.Duration) (*ClusterNode, error) {
+	start := time.Now()
+
+	for {
+		node, err := c.GetClusterNode(before.ID)
+		if err == nil {
+			switch node.Status {
+			case CONNECTED:
+				return node, nil
+			case CONNECTING:
+			default:
+				restarted := node.NodeStartTime.After(before.NodeStartTime)
+
+				if restarted || time.Since(start) >= grace {
+					remaining := timeout - time.Since(start)
+					if remaining < PollInterval {
+						remaining = PollInterval
+					}
+
+					node, err = c.ConnectNode(before.ID, remaining)
+					if err == nil {
+						return node, nil
+					}
+				}
+			}
+		}
+
+		if err != nil && !isTransient(err) {
+			return nil, err
+		}
+
+		if time.Since(start) > timeout {
+			if err != nil {
+				return nil, fmt.Errorf("node %v: %v: %w", before.ID, err, ErrTimeout)
+			}
+
+			return nil, fmt.Errorf("node %v is %v: %w", before.ID, node.Status, ErrTimeout)
+		}
+
+		time.Sleep(PollInterval)
+	}
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, ErrTimeout) {
+		return true
+	}
+
+	var httpError *HTTPError
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
+func (c *Client) checkClusterHealth(exclude string) error {
+	nodes, err := c.ClusterNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		if n.ID != exclude && n.Status != CONNECTED {
+			return fmt.Errorf("node %v: %w", n, ErrClusterUnhealthy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testNode struct {
+	ID        string
+	Address   string
+	Status    string
+	StartTime time.Time
+}
+
+// testCluster is a NiFi stand-in for the cluster endpoints. The nodes change
+// their status immediately and the requests are recorded with the host.
+type testCluster struct {
+	mutex    sync.Mutex
+	port     int
+	nodes    []*testNode
+	requests []string
+}
+
+func (c *testCluster) node(id string) *testNode {
+	for _, n := range c.nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func (c *testCluster) json(n *testNode) map[string]interface{} {
+	return map[string]interface{}{
+		"nodeId":        n.ID,
+		"address":       n.Address,
+		"apiPort":       c.port,
+		"status":        n.Status,
+		"nodeStartTime": n.StartTime.UTC().Format("01/02/2006 15:04:05 MST"),
+	}
+}
+
+func (c *testCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/nifi-api/controller/cluster" {
+		nodes := []interface{}{}
+		for _, n := range c.nodes {
+			nodes = append(nodes, c.json(n))
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"cluster": map[string]interface{}{"nodes": nodes}})
+		return
+	}
+
+	n := c.node(strings.TrimPrefix(r.URL.Path, "/nifi-api/controller/cluster/nodes/"))
+	if n == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var body struct {
+			Node struct {
+				Status string `json:"status"`
+			} `json:"node"`
+		}
+
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.requests = append(c.requests, fmt.Sprintf("%v %v via %v", n.ID, body.Node.Status, strings.Split(r.Host, ":")[0]))
+
+		switch body.Node.Status {
+		case DISCONNECTING:
+			n.Status = DISCONNECTED
+		case OFFLOADING:
+			n.Status = OFFLOADED
+		case CONNECTING:
+			n.Status = CONNECTED
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"node": c.json(n)})
+}
+
+func newTestCluster(t *testing.T) (*testCluster, *Client) {
+	cluster := &testCluster{
+		nodes: []*testNode{
+			{ID: "server", Address: "127.0.0.1", Status: CONNECTED},
+			{ID: "other", Address: "localhost", Status: CONNECTED},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/nifi-api/controller/cluster", cluster)
+	mux.Handle("/nifi-api/controller/cluster/nodes/", cluster)
+
+	client := newTestClient(t, mux)
+
+	port, err := strconv.Atoi(client.server.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster.port = port
+
+	return cluster, client
+}
+
+func setPollInterval(t *testing.T, interval time.Duration) {
+	previous := PollInterval
+	PollInterval = interval
+	t.Cleanup(func() {
+		PollInterval = previous
+	})
+}
+
+func TestRollingRestartOrder(t *testing.T) {
+	setPollInterval(t, time.Millisecond)
+	cluster, client := newTestCluster(t)
+
+	err := client.RollingRestart(&RollingRestartOptions{
+		GracePeriod: time.Hour,
+		Restart: func(node *ClusterNode) error {
+			cluster.mutex.Lock()
+			defer cluster.mutex.Unlock()
+
+			n := cluster.node(node.ID)
+			n.Status = DISCONNECTED
+			n.StartTime = time.Now().Add(time.Hour)
+
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"other DISCONNECTING via 127.0.0.1",
+		"other OFFLOADING via 127.0.0.1",
+		"other CONNECTING via 127.0.0.1",
+		"server DISCONNECTING via localhost",
+		"server OFFLOADING via localhost",
+		"server CONNECTING via localhost",
+	}
+
+	if strings.Join(cluster.requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("requests:\n%v\nwant:\n%v", strings.Join(cluster.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestRollingRestartRejoin(t *testing.T) {
+	setPollInterval(t, time.Millisecond)
+	cluster, client := newTestCluster(t)
+
+	err := client.RollingRestart(&RollingRestartOptions{
+		GracePeriod: time.Hour,
+		Restart: func(node *ClusterNode) error {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+
+				cluster.mutex.Lock()
+				defer cluster.mutex.Unlock()
+
+				cluster.node(node.ID).Status = CONNECTED
+			}()
+
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range cluster.requests {
+		if strings.Contains(r, " "+CONNECTING+" ") {
+			t.Errorf("connect requested for a rejoining node: %v", r)
+		}
+	}
+}
+
+func TestRollingRestartGracePeriod(t *testing.T) {
+	setPollInterval(t, time.Millisecond)
+	cluster, client := newTestCluster(t)
+
+	start := time.Now()
+	err := client.RollingRestart(&RollingRestartOptions{
+		GracePeriod: 20 * time.Millisecond,
+		Restart: func(node *ClusterNode) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if time.Since(start) < 40*time.Millisecond {
+		t.Errorf("connect requested before the grace period")
+	}
+
+	if len(cluster.requests) != 6 {
+		t.Errorf("requests: %v", cluster.requests)
+	}
+}
+
+func TestRollingRestartWithoutOtherNode(t *testing.T) {
+	setPollInterval(t, time.Millisecond)
+	cluster, client := newTestCluster(t)
+
+	cluster.nodes = cluster.nodes[:1]
+
+	err := client.RollingRestart(&RollingRestartOptions{
+		Restart: func(node *ClusterNode) error {
+			return nil
+		},
+	})
+	if err == nil {
+		t.Fatal("restart of the only node through itself")
+	}
+}
+
+func TestIsServer(t *testing.T) {
+	u, _ := url.Parse("https://localhost")
+	if (&Client{server: u}).isServer(&ClusterNode{Address: "LOCALHOST", ApiPort: 443}) == false {
+		t.Error("server with the default port not detected")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type FlowSnapshot struct {
+	Registry string                 `json:"registryId"`
+	Bucket   string                 `json:"bucketId"`
+	Flow     string                 `json:"flowId"`
+	Version  ProcessGroupVersion    `json:"version"`
+	Author   string                 `json:"author"`
+	Contents map[string]interface{} `json:"flowContents"`
+}
+
+func (c *Client) GetFlowSnapshot(registry string, bucket string, flow string, version int) (*FlowSnapshot, error) {
+	registry, bucket, flow, err := c.ResolveFlow(registry, bucket, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("/flow/registries/%v/buckets/%v/flows/%v/versions/%v", registry, bucket, flow, version)
+
+	response, err := c.CallAPI(Get, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var output map[string]interface{}
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFlowSnapshot(registry, bucket, flow, output)
+}
+
+func NewFlowSnapshot(registry string, bucket string, flow string, data map[string]interface{}) (*FlowSnapshot, error) {
+	if val, ok := data["versionedFlowSnapshot"].(map[string]interface{}); ok {
+		data = val
+	}
+
+	contents, ok := data["flowContents"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("flow contents not found")
+	}
+
+	snapshot := &FlowSnapshot{
+		Registry: registry,
+		Bucket:   bucket,
+		Flow:     flow,
+		Contents: contents,
+	}
+
+	if metadata, ok := data["snapshotMetadata"].(map[string]interface{}); ok {
+		if v, ok := metadata["version"].(float64); ok {
+			snapshot.Version.Version = v
+		}
+
+		if t, ok := metadata["timestamp"].(float64); ok {
+			snapshot.Version.Timestamp = time.Unix(int64(t)/1000, 0)
+		}
+
+		if comments, ok := metadata["comments"].(string); ok {
+			snapshot.Version.Comments = comments
+		}
+
+		if author, ok := metadata["author"].(string); ok {
+			snapshot.Author = author
+		}
+	}
+
+	return snapshot, nil
+}
+
+func (c *Client) CompareVersions(registry string, bucket string, flow string, from int, to int) (*FlowDiff, error) {
+	a, err := c.GetFlowSnapshot(registry, bucket, flow, from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.GetFlowSnapshot(registry, bucket, flow, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffSnapshots(a, b), nil
+}
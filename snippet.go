@@ -0,0 +1,233 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Snippet struct {
+	client *Client
+
+	ID            string `json:"id"`
+	ParentGroupID string `json:"parentGroupId"`
+}
+
+type snippetRequest struct {
+	ID                  string               `json:"id,omitempty"`
+	ParentGroupID       string               `json:"parentGroupId"`
+	ProcessGroups       map[string]*Revision `json:"processGroups,omitempty"`
+	RemoteProcessGroups map[string]*Revision `json:"remoteProcessGroups,omitempty"`
+	Processors          map[string]*Revision `json:"processors,omitempty"`
+	Connections         map[string]*Revision `json:"connections,omitempty"`
+	InputPorts          map[string]*Revision `json:"inputPorts,omitempty"`
+	OutputPorts         map[string]*Revision `json:"outputPorts,omitempty"`
+}
+
+func (s *snippetRequest) add(t NiFiType, id string, revision *Revision) error {
+	var m *map[string]*Revision
+
+	switch t {
+	case ProcessGroup:
+		m = &s.ProcessGroups
+	case RemoteProcessGroup:
+		m = &s.RemoteProcessGroups
+	case Processor:
+		m = &s.Processors
+	case Connection:
+		m = &s.Connections
+	case InputPort:
+		m = &s.InputPorts
+	case OutputPort:
+		m = &s.OutputPorts
+	default:
+		return fmt.Errorf("unsupported component type: %v", t)
+	}
+
+	if *m == nil {
+		*m = map[string]*Revision{}
+	}
+
+	(*m)[id] = revision
+
+	return nil
+}
+
+type snippetEntity struct {
+	Snippet                      *snippetRequest `json:"snippet"`
+	DisconnectedNodeAcknowledged bool            `json:"disconnectedNodeAcknowledged"`
+}
+
+// NewSnippet creates a snippet of the components. The revisions are collected
+// from the current component entities and all components must share the same
+// parent process group.
+func (c *Client) NewSnippet(components []*Component) (*Snippet, error) {
+	if len(components) == 0 {
+		return nil, fmt.Errorf("snippet: no components")
+	}
+
+	request := &snippetRequest{}
+
+	for _, component := range components {
+		entity, err := c.GetEntity(component.Type, component.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		revision, err := newRevision(entity, "revision")
+		if err != nil {
+			return nil, err
+		}
+
+		cp, _ := entity["component"].(map[string]interface{})
+		parent, ok := cp["parentGroupId"].(string)
+		if !ok {
+			return nil, fmt.Errorf("snippet: parent group of %v not found", component)
+		}
+
+		if len(request.ParentGroupID) == 0 {
+			request.ParentGroupID = parent
+		} else if request.ParentGroupID != parent {
+			return nil, fmt.Errorf("snippet: %v isn't in process group %v", component, request.ParentGroupID)
+		}
+
+		err = request.add(component.Type, component.ID, revision)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := c.callSnippet(Post, "/snippets", request)
+	if err != nil {
+		return nil, err
+	}
+
+	response.client = c
+
+	return response, nil
+}
+
+func (s *Snippet) Move(groupID string) error {
+	request := &snippetRequest{
+		ID:            s.ID,
+		ParentGroupID: groupID,
+	}
+
+	response, err := s.client.callSnippet(Put, "/snippets/"+s.ID, request)
+	if err != nil {
+		return err
+	}
+
+	s.ParentGroupID = response.ParentGroupID
+
+	return nil
+}
+
+func (s *Snippet) Copy(groupID string, origin Position) ([]*Component, error) {
+	body := map[string]interface{}{
+		"snippetId":                    s.ID,
+		"originX":                      origin.X,
+		"originY":                      origin.Y,
+		"disconnectedNodeAcknowledged": false,
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.Post(fmt.Sprintf("/process-groups/%v/snippet-instance", groupID), data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output map[string]interface{}
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	flow, ok := output["flow"].(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidFormat
+	}
+
+	result := []*Component{}
+	for k, name := range map[string]string{
+		"processGroups":       ProcessGroupName,
+		"remoteProcessGroups": RemoteProcessGroupName,
+		"processors":          ProcessorName,
+		"connections":         ConnectionName,
+		"inputPorts":          InputPortName,
+		"outputPorts":         OutputPortName,
+	} {
+		list, _ := flow[k].([]interface{})
+		for _, v := range list {
+			entity, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			component, ok := entity["component"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			result = append(result, NewComponent(name, "", component))
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteComponents deletes the components of the snippet from the flow, not
+// just the snippet. The components must be stopped and their connections
+// empty.
+func (s *Snippet) DeleteComponents() error {
+	_, err := s.client.Delete("/snippets/"+s.ID, "disconnectedNodeAcknowledged=false")
+	return err
+}
+
+func (c *Client) callSnippet(method Method, path string, request *snippetRequest) (*Snippet, error) {
+	data, err := json.Marshal(&snippetEntity{
+		Snippet: request,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.CallAPI(method, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Snippet *Snippet `json:"snippet"`
+	}
+
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Snippet == nil {
+		return nil, fmt.Errorf("snippet not found")
+	}
+
+	return output.Snippet, nil
+}
@@ -21,16 +21,17 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
-
-	"gopkg.in/square/go-jose.v2/jwt"
 )
 
 type Status struct {
+	// User is the identity of the token and Login the name it was requested
+	// for, which differ with the identity mappings of NiFi.
 	User     string
+	Login    string
 	Token    string
 	Cookies  map[string]string
 	Expire   time.Time
@@ -38,6 +39,12 @@ type Status struct {
 	Insecure bool
 	Server   string
 	CA       string
+
+	source TokenSource
+
+	// mutex guards the token and the cookies, which are cleared by a logout
+	// while the copies of the client send requests.
+	mutex sync.RWMutex
 }
 
 type ByTime []time.Time
@@ -47,14 +54,7 @@ func (a ByTime) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByTime) Less(i, j int) bool { return a[i].Before(a[j]) }
 
 func NewStatus(server *url.URL, token string, cookies []*http.Cookie, ca string, insecure bool) (*Status, error) {
-	var claims map[string]interface{}
-
-	result, err := jwt.ParseSigned(token)
-	if err != nil {
-		return nil, err
-	}
-
-	err = result.UnsafeClaimsWithoutVerification(&claims)
+	info, err := InspectToken(token)
 	if err != nil {
 		return nil, err
 	}
@@ -65,41 +65,79 @@ func NewStatus(server *url.URL, token string, cookies []*http.Cookie, ca string,
 	}
 
 	status := &Status{
+		User:     info.Subject,
 		Token:    token,
 		Cookies:  m,
+		Expire:   info.Expiry,
 		Server:   server.String(),
 		CA:       ca,
 		Insecure: insecure,
 	}
 
-	sub, ok := claims["sub"]
-	if ok {
-		val, ok := sub.(string)
-		if ok {
-			status.User = val
-		}
+	if len(info.Audience) > 0 {
+		status.Aud = info.Audience[0]
 	}
 
-	exp, ok := claims["exp"]
-	if ok {
-		val, ok := exp.(float64)
-		if ok {
-			status.Expire = time.Unix(int64(val), 0)
-		}
+	return status, nil
+}
+
+func (s *Status) loginName() string {
+	if len(s.Login) > 0 {
+		return s.Login
 	}
 
-	aud, ok := claims["aud"]
-	if ok {
-		val, ok := aud.(string)
-		if ok {
-			status.Aud = val
-		}
+	return s.User
+}
+
+func (s *Status) TokenInfo() (*TokenInfo, error) {
+	return InspectToken(s.Token)
+}
+
+// clone copies the status without the lock.
+func (s *Status) clone() *Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cookies := map[string]string{}
+	for k, v := range s.Cookies {
+		cookies[k] = v
 	}
 
-	return status, nil
+	return &Status{
+		User:     s.User,
+		Login:    s.Login,
+		Token:    s.Token,
+		Cookies:  cookies,
+		Expire:   s.Expire,
+		Aud:      s.Aud,
+		Insecure: s.Insecure,
+		Server:   s.Server,
+		CA:       s.CA,
+		source:   s.source,
+	}
+}
+
+func (s *Status) hasToken() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.Token) > 0
+}
+
+// clear removes the token and the cookies after a logout.
+func (s *Status) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Token = ""
+	s.Cookies = map[string]string{}
+	s.Expire = time.Time{}
 }
 
 func (s *Status) GetCookies() []*http.Cookie {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	rc := []*http.Cookie{}
 
 	for k, v := range s.Cookies {
@@ -118,8 +156,21 @@ func (s *Status) NewRequest(method, url string, body io.Reader) (*http.Request,
 		return nil, err
 	}
 
-	if len(s.Token) > 0 {
-		req.Header.Add("Authorization", "Bearer "+s.Token)
+	s.mutex.RLock()
+	token := s.Token
+	s.mutex.RUnlock()
+
+	if s.source != nil {
+		t, err := s.source.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		token = t.AccessToken
+	}
+
+	if len(token) > 0 {
+		req.Header.Add("Authorization", "Bearer "+token)
 	}
 
 	for _, c := range s.GetCookies() {
@@ -141,8 +192,8 @@ func (s *Status) Load(file string) error {
 func (s *Status) Save(file string) error {
 	data, err := yaml.Marshal(s)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	return ioutil.WriteFile(file, data, os.ModePerm)
+	return writeFileAtomic(file, data, 0600)
 }
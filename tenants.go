@@ -0,0 +1,258 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type User struct {
+	ID       string    `json:"id"`
+	Identity string    `json:"identity"`
+	Groups   []string  `json:"userGroups"`
+	Revision *Revision `json:"-"`
+}
+
+type UserGroup struct {
+	ID       string    `json:"id"`
+	Identity string    `json:"identity"`
+	Users    []string  `json:"users"`
+	Revision *Revision `json:"-"`
+}
+
+type tenantReference struct {
+	ID string `json:"id"`
+}
+
+func newTenantReferences(ids []string) []tenantReference {
+	result := []tenantReference{}
+	for _, id := range ids {
+		result = append(result, tenantReference{ID: id})
+	}
+
+	return result
+}
+
+func newTenantReferenceList(ids []string) *[]tenantReference {
+	refs := newTenantReferences(ids)
+	return &refs
+}
+
+func tenantIds(refs []tenantReference) []string {
+	result := []string{}
+	for _, r := range refs {
+		result = append(result, r.ID)
+	}
+
+	return result
+}
+
+type tenantComponent struct {
+	ID         string             `json:"id,omitempty"`
+	Identity   string             `json:"identity"`
+	Users      *[]tenantReference `json:"users,omitempty"`
+	UserGroups []tenantReference  `json:"userGroups,omitempty"`
+}
+
+func (t *tenantComponent) users() []string {
+	if t.Users == nil {
+		return []string{}
+	}
+
+	return tenantIds(*t.Users)
+}
+
+type tenantEntity struct {
+	ID        string           `json:"id,omitempty"`
+	Revision  *Revision        `json:"revision"`
+	Component *tenantComponent `json:"component"`
+}
+
+func (e *tenantEntity) user() *User {
+	return &User{
+		ID:       e.Component.ID,
+		Identity: e.Component.Identity,
+		Groups:   tenantIds(e.Component.UserGroups),
+		Revision: e.Revision,
+	}
+}
+
+func (e *tenantEntity) userGroup() *UserGroup {
+	return &UserGroup{
+		ID:       e.Component.ID,
+		Identity: e.Component.Identity,
+		Users:    e.Component.users(),
+		Revision: e.Revision,
+	}
+}
+
+func (c *Client) Users() ([]*User, error) {
+	entities, err := c.tenants("/tenants/users", "users")
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*User{}
+	for _, e := range entities {
+		result = append(result, e.user())
+	}
+
+	return result, nil
+}
+
+func (c *Client) FindUser(identity string) (*User, error) {
+	users, err := c.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		if u.Identity == identity {
+			return u, nil
+		}
+	}
+
+	return nil, fmt.Errorf("user %v: %w", identity, ErrNotFound)
+}
+
+func (c *Client) CreateUser(identity string) (*User, error) {
+	e, err := c.saveTenant(Post, "/tenants/users", &tenantEntity{
+		Revision:  newRevisionZero(),
+		Component: &tenantComponent{Identity: identity},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.user(), nil
+}
+
+func (c *Client) DeleteUser(user *User) error {
+	return c.deleteWithRevision("/tenants/users/"+user.ID, user.Revision)
+}
+
+func (c *Client) UserGroups() ([]*UserGroup, error) {
+	entities, err := c.tenants("/tenants/user-groups", "userGroups")
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*UserGroup{}
+	for _, e := range entities {
+		result = append(result, e.userGroup())
+	}
+
+	return result, nil
+}
+
+func (c *Client) FindUserGroup(identity string) (*UserGroup, error) {
+	groups, err := c.UserGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		if g.Identity == identity {
+			return g, nil
+		}
+	}
+
+	return nil, fmt.Errorf("user group %v: %w", identity, ErrNotFound)
+}
+
+func (c *Client) CreateUserGroup(identity string, users []string) (*UserGroup, error) {
+	e, err := c.saveTenant(Post, "/tenants/user-groups", &tenantEntity{
+		Revision: newRevisionZero(),
+		Component: &tenantComponent{
+			Identity: identity,
+			Users:    newTenantReferenceList(users),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.userGroup(), nil
+}
+
+func (c *Client) UpdateUserGroup(group *UserGroup) (*UserGroup, error) {
+	e, err := c.saveTenant(Put, "/tenants/user-groups/"+group.ID, &tenantEntity{
+		ID:       group.ID,
+		Revision: group.Revision,
+		Component: &tenantComponent{
+			ID:       group.ID,
+			Identity: group.Identity,
+			Users:    newTenantReferenceList(group.Users),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.userGroup(), nil
+}
+
+func (c *Client) DeleteUserGroup(group *UserGroup) error {
+	return c.deleteWithRevision("/tenants/user-groups/"+group.ID, group.Revision)
+}
+
+func (c *Client) tenants(path string, key string) ([]*tenantEntity, error) {
+	response, err := c.Get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var output map[string][]*tenantEntity
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*tenantEntity{}
+	for _, e := range output[key] {
+		if e.Component != nil {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) saveTenant(method Method, path string, entity *tenantEntity) (*tenantEntity, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.CallAPI(method, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output tenantEntity
+	err = json.Unmarshal([]byte(response), &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Component == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	return &output, nil
+}
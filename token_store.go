@@ -0,0 +1,255 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTokenFile is the token cache of the user, ~ is the home directory.
+const DefaultTokenFile = "~/.nifi/token.yaml"
+
+// TokenStore caches the login status per server and login name. Load returns
+// ErrNotFound, if there is no entry. An empty server matches any server.
+type TokenStore interface {
+	Load(server string, user string) (*Status, error)
+	Save(status *Status) error
+	Delete(server string, user string) error
+}
+
+func tokenKey(server string, user string) string {
+	return server + "|" + user
+}
+
+func findToken(tokens map[string]*Status, server string, user string) (*Status, error) {
+	if status, ok := tokens[tokenKey(server, user)]; ok {
+		return status, nil
+	}
+
+	if len(server) == 0 {
+		for _, status := range tokens {
+			if status.loginName() == user {
+				return status, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("token for %v on %v: %w", user, server, ErrNotFound)
+}
+
+// FileTokenStore keeps the tokens in a YAML file, which may be shared by
+// several processes. Changes are made under a lock of Path.lock and written
+// by an atomic rename.
+type FileTokenStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{
+		Path: path,
+	}
+}
+
+func (s *FileTokenStore) Load(server string, user string) (*Status, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return findToken(tokens, server, user)
+}
+
+func (s *FileTokenStore) Save(status *Status) error {
+	return s.update(func(tokens map[string]*Status) bool {
+		tokens[tokenKey(status.Server, status.loginName())] = status
+		return true
+	})
+}
+
+func (s *FileTokenStore) Delete(server string, user string) error {
+	return s.update(func(tokens map[string]*Status) bool {
+		key := tokenKey(server, user)
+		if _, ok := tokens[key]; !ok {
+			return false
+		}
+
+		delete(tokens, key)
+		return true
+	})
+}
+
+// update changes the entries under the file lock and writes them, if change
+// returns true.
+func (s *FileTokenStore) update(change func(tokens map[string]*Status) bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file := expandPath(s.Path)
+
+	err := os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(file + ".lock")
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if !change(tokens) {
+		return nil
+	}
+
+	return s.write(tokens)
+}
+
+// read loads all entries and accepts the single status written by older
+// versions. Other content is reported as error, so it isn't overwritten.
+func (s *FileTokenStore) read() (map[string]*Status, error) {
+	tokens := map[string]*Status{}
+
+	data, err := ioutil.ReadFile(expandPath(s.Path))
+	if os.IsNotExist(err) {
+		return tokens, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, &tokens)
+	if err == nil {
+		return tokens, nil
+	}
+
+	status := &Status{}
+	if yaml.Unmarshal(data, status) == nil && len(status.Token) > 0 {
+		return map[string]*Status{
+			tokenKey(status.Server, status.loginName()): status,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("token file %v: %w", s.Path, err)
+}
+
+func (s *FileTokenStore) write(tokens map[string]*Status) error {
+	data, err := yaml.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(expandPath(s.Path), data, 0600)
+}
+
+type MemoryTokenStore struct {
+	tokens map[string]*Status
+	mutex  sync.Mutex
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: map[string]*Status{},
+	}
+}
+
+func (s *MemoryTokenStore) Load(server string, user string) (*Status, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, err := findToken(s.tokens, server, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return status.clone(), nil
+}
+
+func (s *MemoryTokenStore) Save(status *Status) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens[tokenKey(status.Server, status.loginName())] = status.clone()
+
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(server string, user string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.tokens, tokenKey(server, user))
+
+	return nil
+}
+
+type NoopTokenStore struct{}
+
+func (NoopTokenStore) Load(server string, user string) (*Status, error) {
+	return nil, fmt.Errorf("token for %v on %v: %w", user, server, ErrNotFound)
+}
+
+func (NoopTokenStore) Save(status *Status) error {
+	return nil
+}
+
+func (NoopTokenStore) Delete(server string, user string) error {
+	return nil
+}
+
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(perm)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
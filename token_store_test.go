@@ -0,0 +1,96 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFileTokenStoreSharedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache", "token.yaml")
+
+	// Every store has its own mutex like the stores of separate processes.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			store := NewFileTokenStore(file)
+			for j := 0; j < 5; j++ {
+				err := store.Save(&Status{
+					Server: "https://nifi:8443",
+					User:   fmt.Sprintf("user-%v-%v", i, j),
+					Token:  "token",
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	tokens, err := NewFileTokenStore(file).read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 40 {
+		t.Errorf("%v tokens saved, want 40", len(tokens))
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+func TestFileTokenStoreLoginName(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.yaml"))
+
+	err := store.Save(&Status{Server: "https://nifi:8443", User: "admin", Login: "admin@EXAMPLE.COM", Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load("https://nifi:8443", "admin@EXAMPLE.COM")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Delete("https://nifi:8443", "admin@EXAMPLE.COM")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load("", "admin@EXAMPLE.COM")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"fmt"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DefaultTokenSkew is the tolerated clock difference to the server.
+const DefaultTokenSkew = time.Minute
+
+var (
+	ErrTokenExpired     = fmt.Errorf("token is expired")
+	ErrTokenNotValidYet = fmt.Errorf("token is not valid yet")
+	ErrInvalidAudience  = fmt.Errorf("invalid audience")
+	ErrInvalidSignature = fmt.Errorf("invalid token signature")
+)
+
+type TokenInfo struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	Expiry    time.Time
+	IssuedAt  time.Time
+	NotBefore time.Time
+}
+
+// InspectToken reads the claims of a JWT without verifying the signature.
+func InspectToken(token string) (*TokenInfo, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	err = parsed.UnsafeClaimsWithoutVerification(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenInfo(&claims), nil
+}
+
+// VerifyToken checks the signature of a JWT with the key of the key set,
+// which matches the key id of the token, and returns the claims.
+func VerifyToken(token string, keys *jose.JSONWebKeySet) (*TokenInfo, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := keys.Keys
+	if len(parsed.Headers) > 0 && len(parsed.Headers[0].KeyID) > 0 {
+		candidates = keys.Key(parsed.Headers[0].KeyID)
+	}
+
+	for _, key := range candidates {
+		var claims jwt.Claims
+		if parsed.Claims(key, &claims) == nil {
+			return newTokenInfo(&claims), nil
+		}
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+func newTokenInfo(claims *jwt.Claims) *TokenInfo {
+	info := &TokenInfo{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Audience: claims.Audience,
+	}
+
+	if claims.Expiry != nil {
+		info.Expiry = claims.Expiry.Time()
+	}
+
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time()
+	}
+
+	if claims.NotBefore != nil {
+		info.NotBefore = claims.NotBefore.Time()
+	}
+
+	return info
+}
+
+// Remaining returns the lifetime left or a negative duration, if the token
+// has expired.
+func (t *TokenInfo) Remaining() time.Duration {
+	return time.Until(t.Expiry)
+}
+
+// Validate checks the expiry, issued-at and not-before times with the skew as
+// tolerance for clock differences and the audience, if it isn't empty. A
+// token, which expires within the skew, is already treated as expired.
+func (t *TokenInfo) Validate(audience string, skew time.Duration) error {
+	now := time.Now()
+
+	if !t.Expiry.IsZero() && !now.Add(skew).Before(t.Expiry) {
+		return fmt.Errorf("%w at %v", ErrTokenExpired, t.Expiry)
+	}
+
+	if !t.NotBefore.IsZero() && now.Add(skew).Before(t.NotBefore) {
+		return fmt.Errorf("%w before %v", ErrTokenNotValidYet, t.NotBefore)
+	}
+
+	if !t.IssuedAt.IsZero() && now.Add(skew).Before(t.IssuedAt) {
+		return fmt.Errorf("%w, issued at %v", ErrTokenNotValidYet, t.IssuedAt)
+	}
+
+	if len(audience) > 0 && !jwt.Audience(t.Audience).Contains(audience) {
+		return fmt.Errorf("%w: %v", ErrInvalidAudience, t.Audience)
+	}
+
+	return nil
+}
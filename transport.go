@@ -0,0 +1,109 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"net/http"
+	"time"
+)
+
+// roundTripper adds the user agent, retries and request logging of the
+// options to the underlying transport.
+type roundTripper struct {
+	next    http.RoundTripper
+	options *Options
+}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.options.UserAgent) > 0 {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.options.UserAgent)
+	}
+
+	retry := t.options.Retry
+	attempts := 1
+	if retry != nil && isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil) {
+		attempts += retry.MaxRetries
+	}
+
+	var response *http.Response
+	var err error
+
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+
+			timer := time.NewTimer(retry.Backoff * time.Duration(1<<uint(i-1)))
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+
+		start := time.Now()
+		response, err = t.next.RoundTrip(req)
+
+		if err != nil {
+			t.options.logf("%v %v: %v (%v)", req.Method, req.URL.Redacted(), err, time.Since(start))
+		} else {
+			t.options.logf("%v %v: %v (%v)", req.Method, req.URL.Redacted(), response.Status, time.Since(start))
+		}
+
+		if i+1 == attempts || !retry.retryable(response, err) {
+			break
+		}
+
+		if response != nil {
+			response.Body.Close()
+		}
+	}
+
+	return response, err
+}
+
+func (p *RetryPolicy) retryable(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	for _, code := range p.StatusCodes {
+		if response.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
@@ -17,9 +17,6 @@ limitations under the License.
 package nifi
 
 import (
-	"encoding/json"
-	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -40,40 +37,11 @@ func (c *Client) Tree(ids []string, types NiFiType) (Tree, error) {
 }
 
 func (c *Client) tree(id string, types NiFiType, recursive bool, filter ComponentFilter) (Tree, error) {
-	data, err := c.Get(fmt.Sprintf("/flow/process-groups/%v/status", id),
-		"recursive="+strconv.FormatBool(recursive),
-	)
-
+	input, err := c.statusSnapshot(id, recursive)
 	if err != nil {
 		return nil, err
 	}
 
-	var input map[string]interface{}
-	err = json.Unmarshal([]byte(data), &input)
-	if err != nil {
-		return nil, err
-	}
-
-	val, ok := input["processGroupStatus"]
-	if !ok {
-		return nil, ErrInvalidFormat
-	}
-
-	input, ok = val.(map[string]interface{})
-	if !ok {
-		return nil, ErrInvalidFormat
-	}
-
-	val, ok = input["aggregateSnapshot"]
-	if !ok {
-		return nil, ErrInvalidFormat
-	}
-
-	input, ok = val.(map[string]interface{})
-	if !ok {
-		return nil, ErrInvalidFormat
-	}
-
 	tree := Tree{}
 	err = c.loopTree("processGroupStatusSnapshots", tree, input, types, filter)
 	if err != nil {
@@ -0,0 +1,314 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type UpgradeStatus string
+
+const (
+	UpgradePlanned  UpgradeStatus = "PLANNED"
+	UpgradeDone     UpgradeStatus = "UPGRADED"
+	UpgradeUpToDate UpgradeStatus = "UP_TO_DATE"
+	UpgradeFailed   UpgradeStatus = "FAILED"
+)
+
+var ErrPinnedNested = fmt.Errorf("pinned process group is nested in a planned upgrade")
+
+type Upgrade struct {
+	Component *Component          `json:"component"`
+	Info      *VersionControlInfo `json:"versionControlInformation"`
+	Current   int                 `json:"current"`
+	Target    int                 `json:"target"`
+	Depth     int                 `json:"depth"`
+
+	ancestors []string
+	pinned    bool
+}
+
+func (u *Upgrade) fullPath() string {
+	return u.Component.Path + "/" + u.Component.Name
+}
+
+func (u *Upgrade) isDescendantOf(o *Upgrade) bool {
+	return contains(u.ancestors, o.Component.ID)
+}
+
+type UpgradePlan []*Upgrade
+
+func (p UpgradePlan) Fprint(w io.Writer) {
+	for _, u := range p {
+		fmt.Fprintf(w, "%s/%s: %v -> %v\n", u.Component.Path, u.Component.Name, u.Current, u.Target)
+	}
+}
+
+type UpgradeResult struct {
+	*Upgrade
+	Status   UpgradeStatus `json:"status"`
+	Error    error         `json:"-"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (r UpgradeResult) MarshalJSON() ([]byte, error) {
+	type plain UpgradeResult
+
+	output := struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{
+		plain: plain(r),
+	}
+
+	if r.Error != nil {
+		output.Error = r.Error.Error()
+	}
+
+	return json.Marshal(output)
+}
+
+type UpgradeReport []*UpgradeResult
+
+func (r UpgradeReport) Failed() UpgradeReport {
+	result := UpgradeReport{}
+	for _, u := range r {
+		if u.Status == UpgradeFailed {
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+func (r UpgradeReport) Fprint(w io.Writer) {
+	for _, u := range r {
+		fmt.Fprintf(w, "%-10s %s/%s: %v -> %v", u.Status, u.Component.Path, u.Component.Name, u.Current, u.Target)
+		if u.Error != nil {
+			fmt.Fprintf(w, " (%v)", u.Error)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+type UpgradeOptions struct {
+	DryRun      bool
+	Concurrency int
+}
+
+type byDepth []*Upgrade
+
+func (a byDepth) Len() int      { return len(a) }
+func (a byDepth) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byDepth) Less(i, j int) bool {
+	if a[i].Depth == a[j].Depth {
+		return a[i].fullPath() < a[j].fullPath()
+	}
+
+	return a[i].Depth < a[j].Depth
+}
+
+// PlanUpgrades collects all versioned process groups below ids which aren't at
+// their target version. The target is taken from pinned, keyed by process group
+// or flow id, and defaults to the latest version in the registry. Groups below
+// a planned group are left out, because their version is set by the snapshot
+// of the outer flow. A pinned version for such a group fails with
+// ErrPinnedNested.
+func (c *Client) PlanUpgrades(ids []string, pinned map[string]int) (UpgradePlan, error) {
+	groups, err := c.All(ids, ProcessGroup, true)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[string]string{}
+	for _, g := range groups {
+		parents[g.ID] = g.ParentID
+	}
+
+	latest := map[string]int{}
+	plan := UpgradePlan{}
+
+	for _, g := range groups {
+		info, _, err := c.GetVersionControlInfo(g.ID)
+		if err == ErrNoVersionControl {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		target, ok := pinned[g.ID]
+		if !ok {
+			target, ok = pinned[info.Flow]
+		}
+
+		isPinned := ok
+		if !ok {
+			key := info.Registry + "/" + info.Bucket + "/" + info.Flow
+			target, ok = latest[key]
+			if !ok {
+				versions, err := c.GetVersions(info.Registry, info.Bucket, info.Flow)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(versions) == 0 {
+					continue
+				}
+
+				target = int(versions[0].Version)
+				latest[key] = target
+			}
+
+			if target <= info.Version {
+				continue
+			}
+		}
+
+		if target == info.Version {
+			continue
+		}
+
+		plan = append(plan, &Upgrade{
+			Component: g,
+			Info:      info,
+			Current:   info.Version,
+			Target:    target,
+			Depth:     strings.Count(g.Path, "/"),
+			ancestors: ancestors(parents, g.ID),
+			pinned:    isPinned,
+		})
+	}
+
+	sort.Sort(byDepth(plan))
+
+	result := UpgradePlan{}
+	for _, u := range plan {
+		var outer *Upgrade
+		for _, o := range result {
+			if u.isDescendantOf(o) {
+				outer = o
+				break
+			}
+		}
+
+		if outer == nil {
+			result = append(result, u)
+		} else if u.pinned {
+			return nil, fmt.Errorf("%v is pinned to version %v inside %v: %w", u.fullPath(), u.Target, outer.fullPath(), ErrPinnedNested)
+		}
+	}
+
+	return result, nil
+}
+
+// Upgrade executes the plan with up to options.Concurrency upgrades at once.
+// The groups of a plan aren't nested, so they can be upgraded independently.
+func (c *Client) Upgrade(plan UpgradePlan, options *UpgradeOptions) UpgradeReport {
+	if options == nil {
+		options = &UpgradeOptions{}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	report := make(UpgradeReport, len(plan))
+	for i, u := range plan {
+		report[i] = &UpgradeResult{
+			Upgrade: u,
+			Status:  UpgradePlanned,
+		}
+	}
+
+	if options.DryRun {
+		return report
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, r := range report {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(r *UpgradeResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			c.upgrade(r)
+		}(r)
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func ancestors(parents map[string]string, id string) []string {
+	result := []string{}
+
+	for parent := parents[id]; len(parent) > 0 && !contains(result, parent); parent = parents[parent] {
+		result = append(result, parent)
+	}
+
+	return result
+}
+
+func (c *Client) upgrade(r *UpgradeResult) {
+	start := time.Now()
+	defer func() {
+		r.Duration = time.Since(start)
+	}()
+
+	info, revision, err := c.GetVersionControlInfo(r.Component.ID)
+	if err != nil {
+		r.Status = UpgradeFailed
+		r.Error = err
+		return
+	}
+
+	if info.Version == r.Target {
+		r.Status = UpgradeUpToDate
+		return
+	}
+
+	result, err := c.SetVersion(info, revision, r.Target)
+	if err != nil {
+		r.Status = UpgradeFailed
+		r.Error = err
+		return
+	}
+
+	if request, ok := result.(map[string]interface{}); ok {
+		if reason, ok := request["failureReason"].(string); ok && len(reason) > 0 {
+			r.Status = UpgradeFailed
+			r.Error = fmt.Errorf("%v", reason)
+			return
+		}
+	}
+
+	r.Status = UpgradeDone
+}
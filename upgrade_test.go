@@ -0,0 +1,252 @@
+/*
+Copyright © 2021 Dirk Lembke
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nifi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	testRegistry = "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0001"
+	testBucket   = "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d0002"
+)
+
+type testGroup struct {
+	id       string
+	name     string
+	flow     string
+	version  int
+	latest   int
+	children []*testGroup
+}
+
+func (g *testGroup) snapshot() map[string]interface{} {
+	children := []interface{}{}
+	for _, c := range g.children {
+		children = append(children, map[string]interface{}{"processGroupStatusSnapshot": c.snapshot()})
+	}
+
+	return map[string]interface{}{
+		"id":                          g.id,
+		"name":                        g.name,
+		"processGroupStatusSnapshots": children,
+	}
+}
+
+func (g *testGroup) find(id string) *testGroup {
+	if g.id == id {
+		return g
+	}
+
+	for _, c := range g.children {
+		if found := c.find(id); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// testFlow is a NiFi stand-in with the process groups
+// U/C, B, A and A/A1 below the root group.
+type testFlow struct {
+	root   *testGroup
+	failed map[string]bool
+}
+
+func newTestFlow() *testFlow {
+	return &testFlow{
+		root: &testGroup{id: "root-id", name: "NiFi Flow", children: []*testGroup{
+			{id: "u", name: "U", children: []*testGroup{
+				{id: "c", name: "C", flow: "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000c", version: 1, latest: 5},
+			}},
+			{id: "b", name: "B", flow: "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000b", version: 1, latest: 4},
+			{id: "a", name: "A", flow: "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000a", version: 1, latest: 3, children: []*testGroup{
+				{id: "a1", name: "A1", flow: "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d00a1", version: 1, latest: 2},
+			}},
+			{id: "d", name: "D", flow: "6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000d", version: 5, latest: 5},
+		}},
+		failed: map[string]bool{},
+	}
+}
+
+func (f *testFlow) client(t *testing.T) *Client {
+	mux := http.NewServeMux()
+
+	write := func(w http.ResponseWriter, status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	mux.HandleFunc("/nifi-api/flow/process-groups/root-id/status", func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusOK, map[string]interface{}{
+			"processGroupStatus": map[string]interface{}{"aggregateSnapshot": f.root.snapshot()},
+		})
+	})
+
+	mux.HandleFunc("/nifi-api/versions/process-groups/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/nifi-api/versions/process-groups/")
+		g := f.root.find(id)
+
+		switch {
+		case g == nil:
+			write(w, http.StatusNotFound, nil)
+		case f.failed[id]:
+			write(w, http.StatusInternalServerError, nil)
+		case len(g.flow) == 0:
+			write(w, http.StatusOK, map[string]interface{}{"processGroupRevision": map[string]interface{}{"version": 1}})
+		default:
+			write(w, http.StatusOK, map[string]interface{}{
+				"processGroupRevision": map[string]interface{}{"version": 1},
+				"versionControlInformation": map[string]interface{}{
+					"groupId":    g.id,
+					"registryId": testRegistry,
+					"bucketId":   testBucket,
+					"flowId":     g.flow,
+					"version":    g.version,
+					"state":      "STALE",
+				},
+			})
+		}
+	})
+
+	mux.HandleFunc("/nifi-api/controller/registry-clients", func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusOK, map[string]interface{}{
+			"registries": []interface{}{map[string]interface{}{"id": testRegistry, "component": map[string]interface{}{"id": testRegistry, "name": "registry"}}},
+		})
+	})
+
+	mux.HandleFunc("/nifi-api/flow/registries/"+testRegistry+"/buckets", func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusOK, map[string]interface{}{
+			"buckets": []interface{}{map[string]interface{}{"id": testBucket, "bucket": map[string]interface{}{"id": testBucket, "name": "bucket"}}},
+		})
+	})
+
+	mux.HandleFunc("/nifi-api/flow/registries/"+testRegistry+"/buckets/"+testBucket+"/flows", func(w http.ResponseWriter, r *http.Request) {
+		flows := []interface{}{}
+		for _, id := range []string{"a", "a1", "b", "c", "d"} {
+			g := f.root.find(id)
+			flows = append(flows, map[string]interface{}{
+				"versionedFlow": map[string]interface{}{"flowId": g.flow, "flowName": g.name},
+			})
+		}
+
+		write(w, http.StatusOK, map[string]interface{}{"versionedFlows": flows})
+	})
+
+	mux.HandleFunc("/nifi-api/flow/registries/"+testRegistry+"/buckets/"+testBucket+"/flows/", func(w http.ResponseWriter, r *http.Request) {
+		flow := strings.Split(strings.TrimPrefix(r.URL.Path, "/nifi-api/flow/registries/"+testRegistry+"/buckets/"+testBucket+"/flows/"), "/")[0]
+
+		latest := 0
+		for _, id := range []string{"a", "a1", "b", "c", "d"} {
+			if g := f.root.find(id); g.flow == flow {
+				latest = g.latest
+			}
+		}
+
+		versions := []interface{}{}
+		for v := 1; v <= latest; v++ {
+			versions = append(versions, map[string]interface{}{
+				"versionedFlowSnapshotMetadata": map[string]interface{}{"version": v, "timestamp": 1600000000000},
+			})
+		}
+
+		write(w, http.StatusOK, map[string]interface{}{"versionedFlowSnapshotMetadataSet": versions})
+	})
+
+	return newTestClient(t, mux)
+}
+
+func TestPlanUpgrades(t *testing.T) {
+	flow := newTestFlow()
+	client := flow.client(t)
+
+	plan, err := client.PlanUpgrades([]string{"root-id"}, map[string]int{
+		"a":                                    3,
+		"6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000c": 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+	plan.Fprint(&output)
+
+	expected := "/NiFi Flow/A: 1 -> 3\n/NiFi Flow/B: 1 -> 4\n/NiFi Flow/U/C: 1 -> 2\n"
+	if output.String() != expected {
+		t.Errorf("plan:\n%v\nwant:\n%v", output.String(), expected)
+	}
+}
+
+func TestPlanUpgradesPinnedNested(t *testing.T) {
+	flow := newTestFlow()
+	client := flow.client(t)
+
+	_, err := client.PlanUpgrades([]string{"root-id"}, map[string]int{"a1": 2})
+	if !errors.Is(err, ErrPinnedNested) {
+		t.Errorf("error = %v, want %v", err, ErrPinnedNested)
+	}
+}
+
+func TestUpgradeReport(t *testing.T) {
+	flow := newTestFlow()
+	client := flow.client(t)
+
+	plan, err := client.PlanUpgrades([]string{"root-id"}, map[string]int{"6f0a3e8e-0d5c-4d4e-9a43-3f1b4c1d000c": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flow.failed["a"] = true
+	flow.root.find("b").version = 4
+
+	report := client.Upgrade(plan, &UpgradeOptions{Concurrency: 2})
+
+	status := map[string]UpgradeStatus{}
+	for _, r := range report {
+		status[r.Component.ID] = r.Status
+	}
+
+	expected := map[string]UpgradeStatus{"a": UpgradeFailed, "b": UpgradeUpToDate}
+	if len(status) != len(expected) || status["a"] != expected["a"] || status["b"] != expected["b"] {
+		t.Errorf("status = %v, want %v", status, expected)
+	}
+
+	data, err := json.Marshal(report.Failed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output []map[string]interface{}
+	err = json.Unmarshal(data, &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output) != 1 || output[0]["status"] != string(UpgradeFailed) || len(output[0]["error"].(string)) == 0 {
+		t.Errorf("unexpected json report: %s", data)
+	}
+
+	if output[0]["target"] != float64(3) {
+		t.Errorf("embedded upgrade is missing in the json report: %s", data)
+	}
+}
@@ -21,12 +21,17 @@ import (
 	"fmt"
 	"net/url"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/zauberhaus/nifi-api-client/filter"
 )
 
+var (
+	ErrNoVersionControl = fmt.Errorf("the process group hasn't version control")
+)
+
 type Revision struct {
 	ClientId string `json:"clientId"`
 	Version  int    `json:"version"`
@@ -91,6 +96,11 @@ func (c *Client) GetVersionControlInfo(id string) (*VersionControlInfo, *Revisio
 }
 
 func (c *Client) GetVersions(registry string, bucket string, flow string) ([]ProcessGroupVersion, error) {
+	registry, bucket, flow, err := c.ResolveFlow(registry, bucket, flow)
+	if err != nil {
+		return nil, err
+	}
+
 	url := fmt.Sprintf("/flow/registries/%v/buckets/%v/flows/%v/versions", registry, bucket, flow)
 
 	response, err := c.CallAPI(Get, url, nil)
@@ -106,8 +116,11 @@ func (c *Client) GetVersions(registry string, bucket string, flow string) ([]Pro
 
 	list := []ProcessGroupVersion{}
 
-	filter.Map(output, ".versionedFlowSnapshotMetadataSet[] | .versionedFlowSnapshotMetadata | { version: .version, timestamp: .timestamp, comments: .comments}", func(val interface{}) (bool, error) {
-		obj := val.(map[string]interface{})
+	err = filter.Map(output, ".versionedFlowSnapshotMetadataSet[] | .versionedFlowSnapshotMetadata | { version: .version, timestamp: .timestamp, comments: .comments}", func(val interface{}) (bool, error) {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return false, ErrInvalidFormat
+		}
 
 		t, ok := obj["timestamp"].(float64)
 		if !ok {
@@ -119,9 +132,11 @@ func (c *Client) GetVersions(registry string, bucket string, flow string) ([]Pro
 			return false, fmt.Errorf("invalid format for version")
 		}
 
+		comments, _ := obj["comments"].(string)
+
 		version := ProcessGroupVersion{
 			Version:   v,
-			Comments:  obj["comments"].(string),
+			Comments:  comments,
 			Timestamp: time.Unix(int64(t)/1000, 0),
 		}
 
@@ -129,6 +144,9 @@ func (c *Client) GetVersions(registry string, bucket string, flow string) ([]Pro
 
 		return true, nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	sort.Sort(ByVersion(list))
 
@@ -144,6 +162,15 @@ func (c *Client) SetVersion(versionInfo *VersionControlInfo, revision *Revision,
 	newVersion := *versionInfo
 	newVersion.Version = version
 
+	registry, bucket, flow, err := c.ResolveFlow(versionInfo.Registry, versionInfo.Bucket, versionInfo.Flow)
+	if err != nil {
+		return nil, err
+	}
+
+	newVersion.Registry = registry
+	newVersion.Bucket = bucket
+	newVersion.Flow = flow
+
 	info := &VersionInfo{
 		Revision: revision,
 		Version:  &newVersion,
@@ -191,9 +218,13 @@ func NewRevision(data interface{}) (*Revision, error) {
 		return nil, fmt.Errorf("nexpected data type: %v", data)
 	}
 
-	revision, ok := m["processGroupRevision"].(map[string]interface{})
+	return newRevision(m, "processGroupRevision")
+}
+
+func newRevision(m map[string]interface{}, key string) (*Revision, error) {
+	revision, ok := m[key].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("unexpected data type: processGroupRevision=%v", revision)
+		return nil, fmt.Errorf("unexpected data type: %v=%v", key, revision)
 	}
 
 	clientId, ok := revision["clientId"].(string)
@@ -209,6 +240,26 @@ func NewRevision(data interface{}) (*Revision, error) {
 	}, nil
 }
 
+func newRevisionZero() *Revision {
+	return &Revision{
+		ClientId: uuid.New().String(),
+		Version:  0,
+	}
+}
+
+func (c *Client) deleteWithRevision(path string, revision *Revision) error {
+	if revision == nil {
+		return fmt.Errorf("revision is missing")
+	}
+
+	_, err := c.Delete(path,
+		"version="+strconv.Itoa(revision.Version),
+		"clientId="+revision.ClientId,
+	)
+
+	return err
+}
+
 func NewVersionControlInfo(data interface{}) (*VersionControlInfo, error) {
 	m, ok := data.(map[string]interface{})
 	if !ok {
@@ -217,7 +268,7 @@ func NewVersionControlInfo(data interface{}) (*VersionControlInfo, error) {
 
 	info, ok := m["versionControlInformation"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("the process group hasn't version control")
+		return nil, ErrNoVersionControl
 	}
 
 	groupId, ok := info["groupId"].(string)